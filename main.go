@@ -231,4 +231,9 @@ func Run() {
 
 func init() {
 	rootCmd.AddCommand(create.Create)
+	rootCmd.AddCommand(create.Migrate)
+	rootCmd.AddCommand(create.Seed)
+	rootCmd.AddCommand(create.Schema)
+	rootCmd.AddCommand(create.Config)
+	rootCmd.AddCommand(create.Secrets)
 }
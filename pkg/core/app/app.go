@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/cache"
@@ -18,7 +19,6 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 type App struct {
@@ -28,17 +28,57 @@ type App struct {
 	HttpPort     int
 	PublicFS     fs.FS
 	Cache        cache.CacheService
-	DB           *pgxpool.Pool
-	MDB          *sql.DB
-	Logger       *zap.Logger
-	FileUpload   *filesystem.FileUploadService
+	// Database is the dialect-agnostic handle repositories should use
+	Database database.DB
+	// Deprecated: use Database. Set only when DBType is postgres.
+	DB *pgxpool.Pool
+	// Deprecated: use Database. Set only when DBType is mysql.
+	MDB        *sql.DB
+	Logger     *zap.Logger
+	FileUpload *filesystem.FileUploadService
+
+	startedMu sync.Mutex // guards started, which Health reads concurrently
+	started   [][]Component
+	ready     atomic.Bool
+}
+
+// Option customises StartApp
+type Option func(*startOptions)
+
+type startOptions struct {
+	autoMigrate   bool
+	migrationsDir string
+	seedsDir      string
+	components    []Component
+}
+
+// WithAutoMigrate applies pending migrations (when Config.Migrate) and seeders
+// (when Config.Seed) during StartApp. Replicas booting together coordinate
+// through a database lock, so only one of them runs each file.
+func WithAutoMigrate() Option {
+	return func(o *startOptions) {
+		o.autoMigrate = true
+	}
+}
+
+// WithMigrationDirs overrides the default "migrations" and "seeds" directories
+func WithMigrationDirs(migrationsDir, seedsDir string) Option {
+	return func(o *startOptions) {
+		o.migrationsDir = migrationsDir
+		o.seedsDir = seedsDir
+	}
 }
 
 func NewApp(cfg *config.Config) *App {
 	return &App{Config: cfg}
 }
 
-func StartApp() (*App, error) {
+func StartApp(opts ...Option) (*App, error) {
+	options := startOptions{migrationsDir: "migrations", seedsDir: "seeds"}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	if err := logger.Init(); err != nil {
 		return nil, fmt.Errorf("error initializing logger: %w", err)
 	}
@@ -47,6 +87,9 @@ func StartApp() (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error loading config: %w", err)
 	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		return nil, err
+	}
 
 	app := &App{
 		Config:       cfg,
@@ -58,38 +101,106 @@ func StartApp() (*App, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := app.initializeResources(ctx); err != nil {
+	components := append(coreComponents(), registeredComponents()...)
+	components = append(components, options.components...)
+	if options.autoMigrate {
+		components = append(components, migrationsComponent(options.migrationsDir, options.seedsDir))
+	}
+
+	if err := app.startComponents(ctx, components); err != nil {
 		return nil, fmt.Errorf("error initializing resources: %w", err)
 	}
 
 	return app, nil
 }
 
-func (app *App) initializeResources(ctx context.Context) error {
-	g, ctx := errgroup.WithContext(ctx)
-
-	g.Go(func() error {
-		var err error
-		app.Cache, err = InitCache(ctx)
-		return err
-	})
-
-	g.Go(func() error {
-		var err error
-		if strings.TrimSpace(app.Config.DBType) == "postgres" {
-			app.DB, err = InitPqDatabase(app.Config)
-		} else if app.Config.DBType == "mysql" {
-			app.MDB, err = InitMySQLDatabase(app.Config)
-		}
-		return err
-	})
-
-	g.Go(func() error {
-		app.FileUpload = filesystem.NewFileUploadService(app.Config)
-		return nil
-	})
-
-	return g.Wait()
+// coreComponents are the resources every App starts: config reloading, cache,
+// database and file uploads
+func coreComponents() []Component {
+	var stopWatch context.CancelFunc
+	var unsubscribe func()
+	return []Component{
+		{
+			Name: "config",
+			Start: func(ctx context.Context, app *App) error {
+				// App.Config stays the startup snapshot; live values come from config.GetConfig
+				unsubscribe = config.OnChange(func(old, new *config.Config) {
+					if old.LogLevel != new.LogLevel {
+						if err := logger.SetLevel(new.LogLevel); err != nil {
+							app.Logger.Error("Failed to change log level", zap.Error(err))
+						}
+					}
+				})
+				if !app.Config.ConfigWatch {
+					return nil
+				}
+				var watchCtx context.Context
+				watchCtx, stopWatch = context.WithCancel(context.Background())
+				return config.Watch(watchCtx)
+			},
+			Stop: func(ctx context.Context, app *App) error {
+				if stopWatch != nil {
+					stopWatch()
+				}
+				if unsubscribe != nil {
+					unsubscribe()
+				}
+				return nil
+			},
+		},
+		{
+			Name: "cache",
+			Start: func(ctx context.Context, app *App) error {
+				var err error
+				app.Cache, err = InitCache(ctx)
+				return err
+			},
+			Stop: func(ctx context.Context, app *App) error {
+				if app.Cache != nil {
+					if err := app.Cache.Close(); err != nil {
+						return fmt.Errorf("failed to close cache: %w", err)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name: "database",
+			Start: func(ctx context.Context, app *App) error {
+				var err error
+				if strings.TrimSpace(app.Config.DBType) == "postgres" {
+					if app.DB, err = InitPqDatabase(app.Config); err == nil {
+						app.Database = database.NewPostgresDB(app.DB)
+					}
+				} else if app.Config.DBType == "mysql" {
+					if app.MDB, err = InitMySQLDatabase(app.Config); err == nil {
+						app.Database = database.NewMySQLDB(app.MDB)
+					}
+				}
+				if err != nil || app.Database == nil {
+					return err
+				}
+				return app.initReplicas()
+			},
+			Stop: func(ctx context.Context, app *App) error {
+				if app.Database != nil {
+					// Closes the primary pool behind app.DB / app.MDB as well
+					app.Database.Close()
+				}
+				return nil
+			},
+			Health: func(ctx context.Context, app *App) error {
+				return app.CheckDatabaseHealth()
+			},
+		},
+		{
+			Name: "filesystem",
+			Start: func(ctx context.Context, app *App) error {
+				app.FileUpload = filesystem.NewFileUploadService(app.Config)
+				return nil
+			},
+		},
+	}
 }
 
 func InitPqDatabase(cfg *config.Config) (*pgxpool.Pool, error) {
@@ -108,52 +219,36 @@ func InitMySQLDatabase(cfg *config.Config) (*sql.DB, error) {
 	return dbService.GetDB(), nil
 }
 
+// initReplicas wraps app.Database so reads are spread over the configured replicas
+func (app *App) initReplicas() error {
+	replicas, err := database.OpenReplicas(app.Config)
+	if err != nil {
+		return err
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	interval := time.Duration(app.Config.DBReplicaInterval) * time.Second
+	replicated := database.NewReplicatedDB(app.Database, replicas, interval)
+	app.Logger.Info("Read replicas configured",
+		zap.Int("replicas", len(replicas)),
+		zap.Int("healthy", replicated.HealthyReplicas()))
+	app.Database = replicated
+	return nil
+}
+
 func InitCache(ctx context.Context) (cache.CacheService, error) {
-	return cache.NewRedisCacheService(ctx)
+	return cache.New(ctx)
 }
 
+// CloseResources stops every started component in reverse dependency order
 func (app *App) CloseResources() error {
-	var wg sync.WaitGroup
-	errChan := make(chan error, 3)
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if app.Cache != nil {
-			if err := app.Cache.Close(); err != nil {
-				errChan <- fmt.Errorf("failed to close cache: %w", err)
-			}
-		}
-	}()
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if app.DB != nil {
-			app.DB.Close()
-		}
-	}()
-
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if app.MDB != nil {
-			if err := app.MDB.Close(); err != nil {
-				errChan <- fmt.Errorf("failed to close MySQL database: %w", err)
-			}
-		}
-	}()
-
-	wg.Wait()
-	close(errChan)
-
-	var errs []error
-	for err := range errChan {
-		errs = append(errs, err)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	if len(errs) > 0 {
-		return fmt.Errorf("errors closing resources: %v", errs)
+	if err := app.stopComponents(ctx); err != nil {
+		return fmt.Errorf("errors closing resources: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Component is a resource whose lifecycle is managed by App. Components start
+// in dependency order, with independent components starting in parallel, and
+// stop in reverse order. Any hook may be nil.
+type Component struct {
+	Name      string
+	DependsOn []string
+	Start     func(ctx context.Context, app *App) error
+	Stop      func(ctx context.Context, app *App) error
+	Health    func(ctx context.Context, app *App) error
+}
+
+// ComponentHealth is the health of a single component
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Component
+)
+
+// Register adds a component to every App started afterwards. It is meant to be
+// called from package init functions, the way database/sql drivers register.
+func Register(c Component) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// WithComponent adds a component to this StartApp call only
+func WithComponent(c Component) Option {
+	return func(o *startOptions) {
+		o.components = append(o.components, c)
+	}
+}
+
+func registeredComponents() []Component {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]Component(nil), registry...)
+}
+
+// componentLayers groups components so that every component comes after all
+// of its dependencies; components within a layer are independent.
+func componentLayers(components []Component) ([][]Component, error) {
+	byName := make(map[string]Component, len(components))
+	for _, c := range components {
+		if c.Name == "" {
+			return nil, errors.New("component name is required")
+		}
+		if _, ok := byName[c.Name]; ok {
+			return nil, fmt.Errorf("component %s registered twice", c.Name)
+		}
+		byName[c.Name] = c
+	}
+
+	remaining := make(map[string]int, len(components))
+	dependents := make(map[string][]string)
+	for _, c := range components {
+		for _, dep := range c.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", c.Name, dep)
+			}
+			dependents[dep] = append(dependents[dep], c.Name)
+		}
+		remaining[c.Name] = len(c.DependsOn)
+	}
+
+	var layers [][]Component
+	for len(remaining) > 0 {
+		var ready []string
+		for name, count := range remaining {
+			if count == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, errors.New("component dependencies contain a cycle")
+		}
+		sort.Strings(ready)
+
+		layer := make([]Component, 0, len(ready))
+		for _, name := range ready {
+			layer = append(layer, byName[name])
+			delete(remaining, name)
+			for _, dependent := range dependents[name] {
+				remaining[dependent]--
+			}
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// startComponents starts every component layer by layer. If a component fails,
+// the ones already started are stopped again before the error is returned.
+func (app *App) startComponents(ctx context.Context, components []Component) error {
+	layers, err := componentLayers(components)
+	if err != nil {
+		return err
+	}
+
+	for _, layer := range layers {
+		g, gctx := errgroup.WithContext(ctx)
+		for _, c := range layer {
+			c := c
+			g.Go(func() error {
+				if c.Start == nil {
+					return nil
+				}
+				if err := c.Start(gctx, app); err != nil {
+					return fmt.Errorf("failed to start %s: %w", c.Name, err)
+				}
+				return nil
+			})
+		}
+
+		err := g.Wait()
+		// Record the layer even on failure so partially started components are stopped
+		app.startedMu.Lock()
+		app.started = append(app.started, layer)
+		app.startedMu.Unlock()
+		if err != nil {
+			stopCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if stopErr := app.stopComponents(stopCtx); stopErr != nil {
+				return errors.Join(err, stopErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stopComponents stops the started components in reverse start order
+func (app *App) stopComponents(ctx context.Context) error {
+	// Take the layers up front so Health stops reporting components being stopped
+	app.startedMu.Lock()
+	started := app.started
+	app.started = nil
+	app.startedMu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, c := range started[i] {
+			if c.Stop == nil {
+				continue
+			}
+			c := c
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := c.Stop(ctx, app); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to stop %s: %w", c.Name, err))
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	return errors.Join(errs...)
+}
+
+// Health runs the health hook of every started component
+func (app *App) Health(ctx context.Context) []ComponentHealth {
+	app.startedMu.Lock()
+	started := app.started
+	app.startedMu.Unlock()
+
+	var report []ComponentHealth
+	for _, layer := range started {
+		for _, c := range layer {
+			if c.Health == nil {
+				continue
+			}
+			status := ComponentHealth{Name: c.Name, Healthy: true}
+			if err := c.Health(ctx, app); err != nil {
+				status.Healthy = false
+				status.Error = err.Error()
+			}
+			report = append(report, status)
+		}
+	}
+	return report
+}
@@ -0,0 +1,15 @@
+package app
+
+import "github.com/JubaerHossain/rootx/pkg/core/config"
+
+// ConfigSection returns the current value of a section registered with
+// config.Register, e.g. app.ConfigSection[PaymentConfig](application, "PAYMENT").
+// It follows config reloads, unlike app.Config which is the startup snapshot.
+func ConfigSection[T any](app *App, prefix string) *T {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		cfg = app.Config
+	}
+	value, _ := cfg.Section(prefix).(*T)
+	return value
+}
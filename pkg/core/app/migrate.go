@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JubaerHossain/rootx/pkg/core/migration"
+	"go.uber.org/zap"
+)
+
+// MigrationConn returns a migration runner connection for the configured database
+func (app *App) MigrationConn() (migration.Conn, error) {
+	switch {
+	case app.DB != nil:
+		return migration.NewPgxConn(app.DB), nil
+	case app.MDB != nil:
+		return migration.NewSQLConn(app.MDB, migration.MySQL), nil
+	}
+	return nil, errors.New("database connection is not initialized")
+}
+
+// migrationsComponent runs autoMigrate once the database is up
+func migrationsComponent(migrationsDir, seedsDir string) Component {
+	return Component{
+		Name:      "migrations",
+		DependsOn: []string{"database"},
+		Start: func(ctx context.Context, app *App) error {
+			// Migrations may wait on another replica's lock, so they outlive the boot timeout
+			return app.autoMigrate(context.WithoutCancel(ctx), migrationsDir, seedsDir)
+		},
+	}
+}
+
+// autoMigrate runs pending migrations and seeders according to Config.Migrate and Config.Seed
+func (app *App) autoMigrate(ctx context.Context, migrationsDir, seedsDir string) error {
+	if !app.Config.Migrate && !app.Config.Seed {
+		return nil
+	}
+
+	conn, err := app.MigrationConn()
+	if err != nil {
+		return err
+	}
+
+	if app.Config.Migrate {
+		result, err := migration.RunMigrations(ctx, conn, migrationsDir)
+		if err != nil {
+			return err
+		}
+		app.Logger.Info("Migrations applied",
+			zap.Strings("applied", result.Applied),
+			zap.Strings("recorded", result.Recorded),
+		)
+	}
+
+	if app.Config.Seed {
+		result, err := migration.RunSeeders(ctx, conn, migration.SeedOptions{Dir: seedsDir, Env: app.Config.AppEnv})
+		if errors.Is(err, migration.ErrProductionSeed) {
+			app.Logger.Warn("Skipping seeders in production; run `rootx seed --force` to seed explicitly")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		app.Logger.Info("Seeders applied", zap.Strings("applied", result.Applied))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultShutdownTimeout bounds HTTP draining and resource closing when SHUTDOWN_TIMEOUT is unset
+const defaultShutdownTimeout = 30 * time.Second
+
+// Ready reports whether the server is accepting traffic. It turns false as
+// soon as shutdown begins so readiness probes take the instance out of rotation.
+func (app *App) Ready() bool {
+	return app.ready.Load()
+}
+
+// Run serves handler until ctx is cancelled, SIGINT/SIGTERM arrives or the
+// server fails, then shuts down gracefully: readiness flips to false, the
+// server waits SHUTDOWN_DRAIN_WAIT seconds for load balancers to notice,
+// in-flight requests drain within SHUTDOWN_TIMEOUT, and all components are closed.
+func (app *App) Run(ctx context.Context, handler http.Handler) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app.SetupHTTPServer(handler)
+
+	addr := app.HttpServer.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		err = fmt.Errorf("could not start server: %w", err)
+		if shutdownErr := app.Shutdown(); shutdownErr != nil {
+			return errors.Join(err, shutdownErr)
+		}
+		return err
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		app.Logger.Info("Starting HTTP server", zap.Int("port", app.Config.AppPort))
+		if err := app.HttpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("server failed: %w", err)
+		}
+		close(serverErr)
+	}()
+	// The port is bound, so connections queue even before Serve accepts them
+	app.ready.Store(true)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		// Restore default signal handling so a second signal forces exit during the drain
+		stop()
+		app.Logger.Info("Shutdown signal received")
+	case err := <-serverErr:
+		if err != nil {
+			app.Logger.Error("HTTP server error", zap.Error(err))
+			runErr = err
+		}
+	}
+
+	if err := app.Shutdown(); err != nil {
+		return errors.Join(runErr, err)
+	}
+	return runErr
+}
+
+// Shutdown flips readiness, drains the HTTP server and closes every component
+func (app *App) Shutdown() error {
+	app.ready.Store(false)
+
+	if wait := time.Duration(app.Config.ShutdownDrainWait) * time.Second; wait > 0 && app.HttpServer != nil {
+		app.Logger.Info("Waiting for load balancers to drain traffic", zap.Duration("wait", wait))
+		time.Sleep(wait)
+	}
+
+	timeout := time.Duration(app.Config.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if app.HttpServer != nil {
+		app.Logger.Info("Shutting down HTTP server...")
+		if err := app.HttpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("could not gracefully shutdown the HTTP server: %w", err))
+		}
+	}
+
+	if err := app.stopComponents(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("errors closing resources: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	app.Logger.Info("HTTP server gracefully stopped")
+	return nil
+}
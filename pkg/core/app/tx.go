@@ -0,0 +1,17 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+)
+
+// WithTx runs fn in a transaction on app.Database. Repositories called with the
+// ctx passed to fn join the transaction; see database.WithTx for nesting and retries.
+func (app *App) WithTx(ctx context.Context, fn func(ctx context.Context) error, opts ...database.TxOption) error {
+	if app.Database == nil {
+		return errors.New("database connection is not initialized")
+	}
+	return database.WithTx(ctx, app.Database, fn, opts...)
+}
@@ -4,67 +4,103 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/config"
 	"github.com/go-redis/redis/v8"
 )
 
+// CacheService is implemented by RedisCacheService and, when Redis is
+// disabled, MemoryCacheService. A miss is reported as "" with a nil error.
 type CacheService interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key, value string, expiration time.Duration) error
+	// SetWithTags sets value and associates key with tags such as "products"
+	// or "product:42" so InvalidateTags can remove it without scanning keys
+	SetWithTags(ctx context.Context, key, value string, expiration time.Duration, tags ...string) error
+	InvalidateTags(ctx context.Context, tags ...string) (int64, error)
 	Remove(ctx context.Context, key string) error
 	CountKeys(ctx context.Context) (int64, error)
 	ClearPattern(ctx context.Context, pattern string) (int64, error)
 	Close() error
 }
 
+// TagPrefix prefixes the Redis sets holding the keys of each tag
+const TagPrefix = "rootx:tag:"
+
+// tagScript adds ARGV[2] to the tag set KEYS[1] and makes the set live at
+// least as long as the entry (ARGV[1] milliseconds, 0 for no expiry)
+var tagScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[2])
+local ttl = tonumber(ARGV[1])
+if ttl <= 0 then
+	redis.call('PERSIST', KEYS[1])
+	return 1
+end
+local current = redis.call('PTTL', KEYS[1])
+if existed == 0 or (current >= 0 and current < ttl) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`)
+
 // RedisCacheService implements CacheService using Redis
 type RedisCacheService struct {
-	client *redis.Client
+	client redis.UniversalClient
+}
+
+// New returns the cache selected by the configuration: Redis when IS_REDIS is
+// set (behind an L1 of CACHE_L1_SIZE entries when CACHE_L1 is set), otherwise
+// an in-memory LRU of CACHE_MEMORY_SIZE entries
+func New(ctx context.Context) (CacheService, error) {
+	cfg := config.GetConfig()
+	if !cfg.IsRedis {
+		return NewMemoryCacheService(cfg.CacheMemorySize), nil
+	}
+	redisCache, err := NewRedisCacheService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.CacheL1 {
+		return redisCache, nil
+	}
+	tiered, err := NewTieredCacheService(ctx, redisCache, cfg.CacheL1Size, cfg.CacheL1TTL)
+	if err != nil {
+		redisCache.Close()
+		return nil, err
+	}
+	return tiered, nil
 }
 
 // NewRedisCacheService creates a new instance of RedisCacheService
 func NewRedisCacheService(ctx context.Context) (*RedisCacheService, error) {
-	// Get Redis server address and password from environment variables
-	if config.GlobalConfig.IsRedis {
-		redisURI := config.GlobalConfig.RedisURI
-		if redisURI == "" {
-			redisURI = "localhost:6379" // Default Redis server address
-		}
-		redisPassword := config.GlobalConfig.RedisPassword
-		if redisPassword == "" {
-			redisPassword = "" // No password if not provided
-		}
-
-		redisDB := config.GlobalConfig.RedisDB
-		if redisDB == -1 {
-			redisDB = 0
-		}
-
-		// Create a new Redis client
-		client := redis.NewClient(&redis.Options{
-			Addr:     redisURI,
-			Password: redisPassword,
-			DB:       redisDB,
-		})
+	cfg := config.GetConfig()
+	if !cfg.IsRedis {
+		return nil, errors.New("redis is disabled, set IS_REDIS=true or use cache.New")
+	}
 
-		// Ping the Redis server to ensure connectivity
-		if err := client.Ping(ctx).Err(); err != nil {
-			return nil, fmt.Errorf("failed to ping Redis server: %w", err)
-		}
+	// Create a single, sentinel or cluster client following REDIS_MODE
+	client, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		return &RedisCacheService{
-			client: client,
-		}, nil
-	} else {
-		return nil, nil
+	// Ping the Redis server to ensure connectivity
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to ping Redis server: %w", err)
 	}
+
+	return &RedisCacheService{
+		client: client,
+	}, nil
 }
 
 // Get retrieves value from cache by key
 func (svc *RedisCacheService) Get(ctx context.Context, key string) (string, error) {
-	if config.GlobalConfig.IsRedis {
+	if config.GetConfig().IsRedis {
 		val, err := svc.client.Get(ctx, key).Result()
 		if err != nil {
 			if errors.Is(err, redis.Nil) {
@@ -80,7 +116,7 @@ func (svc *RedisCacheService) Get(ctx context.Context, key string) (string, erro
 
 // Set sets value in cache with specified key
 func (svc *RedisCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
-	if config.GlobalConfig.IsRedis {
+	if config.GetConfig().IsRedis {
 		err := svc.client.Set(ctx, key, value, expiration).Err()
 		if err != nil {
 			return fmt.Errorf("failed to set value in cache: %w", err)
@@ -91,10 +127,77 @@ func (svc *RedisCacheService) Set(ctx context.Context, key, value string, expira
 	}
 }
 
+// SetWithTags sets value and adds key to the Redis set of each tag
+func (svc *RedisCacheService) SetWithTags(ctx context.Context, key, value string, expiration time.Duration, tags ...string) error {
+	if !config.GetConfig().IsRedis {
+		return nil
+	}
+	// Not a transaction: the key and its tag sets may live on different
+	// cluster slots
+	_, err := svc.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, expiration)
+		for _, tag := range tags {
+			tagScript.Eval(ctx, pipe, []string{TagPrefix + tag}, expiration.Milliseconds(), key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set value in cache: %w", err)
+	}
+	return nil
+}
+
+// InvalidateTags deletes the keys associated with tags; the cost is
+// proportional to the number of tagged entries, not the keyspace
+func (svc *RedisCacheService) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	deleted, _, err := svc.invalidateTags(ctx, tags...)
+	return deleted, err
+}
+
+// invalidateTags also returns the keys that were tagged
+func (svc *RedisCacheService) invalidateTags(ctx context.Context, tags ...string) (int64, []string, error) {
+	if !config.GetConfig().IsRedis {
+		return 0, nil, nil
+	}
+	var deleted int64
+	var invalidated []string
+	for _, tag := range tags {
+		tagKey := TagPrefix + tag
+		keys, err := svc.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return deleted, invalidated, fmt.Errorf("failed to read cache tag %s: %w", tag, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		// Delete key by key so it works across cluster slots, and only remove
+		// the members read so keys tagged meanwhile stay tracked
+		members := make([]interface{}, len(keys))
+		dels := make([]*redis.IntCmd, len(keys))
+		_, err = svc.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				dels[i] = pipe.Del(ctx, key)
+				members[i] = key
+			}
+			pipe.SRem(ctx, tagKey, members...)
+			return nil
+		})
+		if err != nil {
+			return deleted, invalidated, fmt.Errorf("failed to invalidate cache tag %s: %w", tag, err)
+		}
+		for _, del := range dels {
+			deleted += del.Val()
+		}
+		invalidated = append(invalidated, keys...)
+	}
+	return deleted, invalidated, nil
+}
+
 // Remove implements CacheService.
 func (svc *RedisCacheService) Remove(ctx context.Context, key string) error {
 	// Use context with timeout to prevent blocking indefinitely
-	if config.GlobalConfig.IsRedis {
+	if config.GetConfig().IsRedis {
 		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
@@ -111,79 +214,85 @@ func (svc *RedisCacheService) Remove(ctx context.Context, key string) error {
 
 // CountKeys counts the number of keys in the Redis cache
 func (svc *RedisCacheService) CountKeys(ctx context.Context) (int64, error) {
-	// Use SCAN command to iterate over keys in the cache
-	if config.GlobalConfig.IsRedis {
-		var cursor uint64 = 0
-		var keysCount int64 = 0
-
-		for {
-			// Scan keys with cursor and pattern
-			keys, nextCursor, err := svc.client.Scan(ctx, cursor, "*", 100).Result()
-			if err != nil {
-				return 0, fmt.Errorf("failed to scan keys in cache: %w", err)
-			}
-
-			// Increment keys count
-			keysCount += int64(len(keys))
-
-			// Update cursor for next iteration
-			cursor = nextCursor
-
-			// Break if iteration is complete
-			if cursor == 0 {
-				break
-			}
-		}
-
-		return keysCount, nil
-	} else {
+	if !config.GetConfig().IsRedis {
 		return 0, nil
 	}
+	var keysCount int64
+	err := svc.scan(ctx, "*", func(ctx context.Context, node *redis.Client, keys []string) error {
+		atomic.AddInt64(&keysCount, int64(len(keys)))
+		return nil
+	})
+	return keysCount, err
 }
 
 func (svc *RedisCacheService) ClearPattern(ctx context.Context, pattern string) (int64, error) {
 	// Use context with timeout to prevent blocking indefinitely
-	if !config.GlobalConfig.IsRedis {
+	if !config.GetConfig().IsRedis {
 		return 0, nil
 	}
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.GlobalConfig.RedisExp)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.GetConfig().RedisExp)*time.Second)
 	defer cancel()
 
-	// Use SCAN command to iterate over keys in the cache matching the pattern
-	var cursor uint64 = 0
-	var deletedKeysCount int64 = 0
-
-	for {
-		// Scan keys with cursor and pattern
-		keys, nextCursor, err := svc.client.Scan(ctx, cursor, pattern, 100).Result()
+	var deletedKeysCount int64
+	err := svc.scan(ctx, pattern, func(ctx context.Context, node *redis.Client, keys []string) error {
+		// Delete key by key: in cluster mode a node holds many hash slots and a
+		// multi-key DEL across slots is rejected
+		dels := make([]*redis.IntCmd, len(keys))
+		_, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				dels[i] = pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		for _, del := range dels {
+			atomic.AddInt64(&deletedKeysCount, del.Val())
+		}
 		if err != nil {
-			return deletedKeysCount, fmt.Errorf("failed to scan keys in cache: %w", err)
+			return fmt.Errorf("failed to delete keys in cache: %w", err)
 		}
+		return nil
+	})
+	return deletedKeysCount, err
+}
 
-		// Delete keys matching the pattern
-		if len(keys) > 0 {
-			deletedCount, err := svc.client.Del(ctx, keys...).Result()
+// scan calls fn with each batch of keys matching pattern. In cluster mode
+// every master is scanned, concurrently, so fn must be safe for that.
+func (svc *RedisCacheService) scan(ctx context.Context, pattern string, fn func(ctx context.Context, node *redis.Client, keys []string) error) error {
+	scanNode := func(ctx context.Context, node *redis.Client) error {
+		var cursor uint64
+		for {
+			// Scan keys with cursor and pattern
+			keys, nextCursor, err := node.Scan(ctx, cursor, pattern, 100).Result()
 			if err != nil {
-				return deletedKeysCount, fmt.Errorf("failed to delete keys in cache: %w", err)
+				return fmt.Errorf("failed to scan keys in cache: %w", err)
+			}
+			if len(keys) > 0 {
+				if err := fn(ctx, node, keys); err != nil {
+					return err
+				}
 			}
-			deletedKeysCount += deletedCount
-		}
-
-		// Update cursor for next iteration
-		cursor = nextCursor
 
-		// Break if iteration is complete
-		if cursor == 0 {
-			break
+			// Break if iteration is complete
+			cursor = nextCursor
+			if cursor == 0 {
+				return nil
+			}
 		}
 	}
 
-	return deletedKeysCount, nil
+	switch client := svc.client.(type) {
+	case *redis.ClusterClient:
+		return client.ForEachMaster(ctx, scanNode)
+	case *redis.Client:
+		return scanNode(ctx, client)
+	default:
+		return fmt.Errorf("unsupported redis client %T", svc.client)
+	}
 }
 
 // Close closes the Redis client
 func (svc *RedisCacheService) Close() error {
-	if config.GlobalConfig.IsRedis {
+	if config.GetConfig().IsRedis {
 		return svc.client.Close()
 	} else {
 		return nil
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMemorySize is the number of entries a MemoryCacheService keeps when
+// no size is configured
+const DefaultMemorySize = 10000
+
+// memoryEntry is one cached value; expiresAt is zero for entries without a TTL
+type memoryEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+	tags      []string
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryCacheService implements CacheService in process, evicting the least
+// recently used entry once it holds size entries. It is used when Redis is
+// disabled; entries are not shared between instances.
+type MemoryCacheService struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{} // tag -> keys
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryCacheService creates an in-memory cache holding up to size entries
+// (DefaultMemorySize when size <= 0). Expired entries are dropped on access
+// and swept every minute until Close.
+func NewMemoryCacheService(size int) *MemoryCacheService {
+	if size <= 0 {
+		size = DefaultMemorySize
+	}
+	svc := &MemoryCacheService{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		tags:    make(map[string]map[string]struct{}),
+		stop:    make(chan struct{}),
+	}
+	go svc.sweep(time.Minute)
+	return svc
+}
+
+// Get retrieves value from cache by key, returning "" on a miss like the Redis service
+func (svc *MemoryCacheService) Get(ctx context.Context, key string) (string, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	element, ok := svc.entries[key]
+	if !ok {
+		return "", nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if entry.expired(time.Now()) {
+		svc.removeElement(element)
+		return "", nil
+	}
+	svc.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+// Set sets value in cache with specified key; expiration 0 means no expiry
+func (svc *MemoryCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	return svc.SetWithTags(ctx, key, value, expiration)
+}
+
+// SetWithTags sets value and replaces the tags the key is associated with
+func (svc *MemoryCacheService) SetWithTags(ctx context.Context, key, value string, expiration time.Duration, tags ...string) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var expiresAt time.Time
+	if expiration > 0 {
+		expiresAt = time.Now().Add(expiration)
+	}
+	if element, ok := svc.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		svc.untag(entry)
+		entry.value, entry.expiresAt, entry.tags = value, expiresAt, tags
+		svc.tag(entry)
+		svc.order.MoveToFront(element)
+		return nil
+	}
+
+	entry := &memoryEntry{key: key, value: value, expiresAt: expiresAt, tags: tags}
+	svc.entries[key] = svc.order.PushFront(entry)
+	svc.tag(entry)
+	for svc.order.Len() > svc.size {
+		svc.removeElement(svc.order.Back())
+	}
+	return nil
+}
+
+// InvalidateTags removes every entry associated with one of tags
+func (svc *MemoryCacheService) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var deleted int64
+	for _, tag := range tags {
+		for key := range svc.tags[tag] {
+			if element, ok := svc.entries[key]; ok {
+				svc.removeElement(element)
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// Remove implements CacheService.
+func (svc *MemoryCacheService) Remove(ctx context.Context, key string) error {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	if element, ok := svc.entries[key]; ok {
+		svc.removeElement(element)
+	}
+	return nil
+}
+
+// CountKeys counts the entries that have not expired
+func (svc *MemoryCacheService) CountKeys(ctx context.Context) (int64, error) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	now := time.Now()
+	var count int64
+	for _, element := range svc.entries {
+		if !element.Value.(*memoryEntry).expired(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ClearPattern removes the keys matching a Redis style glob (*, ?, [abc], \ escapes)
+func (svc *MemoryCacheService) ClearPattern(ctx context.Context, pattern string) (int64, error) {
+	matcher, err := globRegexp(pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var deleted int64
+	for key, element := range svc.entries {
+		if matcher.MatchString(key) {
+			svc.removeElement(element)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Close stops the expiry sweep
+func (svc *MemoryCacheService) Close() error {
+	svc.once.Do(func() { close(svc.stop) })
+	return nil
+}
+
+func (svc *MemoryCacheService) removeElement(element *list.Element) {
+	entry := element.Value.(*memoryEntry)
+	svc.order.Remove(element)
+	delete(svc.entries, entry.key)
+	svc.untag(entry)
+}
+
+func (svc *MemoryCacheService) tag(entry *memoryEntry) {
+	for _, tag := range entry.tags {
+		keys, ok := svc.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			svc.tags[tag] = keys
+		}
+		keys[entry.key] = struct{}{}
+	}
+}
+
+func (svc *MemoryCacheService) untag(entry *memoryEntry) {
+	for _, tag := range entry.tags {
+		delete(svc.tags[tag], entry.key)
+		if len(svc.tags[tag]) == 0 {
+			delete(svc.tags, tag)
+		}
+	}
+}
+
+// sweep drops expired entries so keys that are never read again do not pin memory
+func (svc *MemoryCacheService) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-svc.stop:
+			return
+		case <-ticker.C:
+			svc.mu.Lock()
+			now := time.Now()
+			for _, element := range svc.entries {
+				if element.Value.(*memoryEntry).expired(now) {
+					svc.removeElement(element)
+				}
+			}
+			svc.mu.Unlock()
+		}
+	}
+}
+
+// globRegexp translates a Redis glob pattern into an anchored regexp
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			b.WriteByte(c)
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
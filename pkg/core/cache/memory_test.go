@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// expire moves the expiry of key into the past without waiting for it
+func expire(svc *MemoryCacheService, key string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	svc.entries[key].Value.(*memoryEntry).expiresAt = time.Now().Add(-time.Second)
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMemoryCacheService(2)
+	defer svc.Close()
+
+	svc.Set(ctx, "a", "1", 0)
+	svc.Set(ctx, "b", "2", 0)
+	svc.Get(ctx, "a") // b is now the least recently used
+	svc.Set(ctx, "c", "3", 0)
+
+	for key, want := range map[string]string{"a": "1", "b": "", "c": "3"} {
+		if got, _ := svc.Get(ctx, key); got != want {
+			t.Errorf("Get(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	// Overwriting an existing key does not evict anything
+	svc.Set(ctx, "c", "4", 0)
+	if n, _ := svc.CountKeys(ctx); n != 2 {
+		t.Errorf("CountKeys() = %d, want 2", n)
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMemoryCacheService(0)
+	defer svc.Close()
+
+	svc.Set(ctx, "short", "x", time.Minute)
+	svc.Set(ctx, "forever", "y", 0)
+	expire(svc, "short")
+
+	if n, _ := svc.CountKeys(ctx); n != 1 {
+		t.Errorf("CountKeys() = %d, want expired keys left out", n)
+	}
+	if got, _ := svc.Get(ctx, "short"); got != "" {
+		t.Errorf("Get(short) = %q, want a miss after expiry", got)
+	}
+	if _, ok := svc.entries["short"]; ok {
+		t.Error("expired entry was not dropped on access")
+	}
+	if got, _ := svc.Get(ctx, "forever"); got != "y" {
+		t.Errorf("Get(forever) = %q, want y", got)
+	}
+
+	// Setting again without a TTL clears the expiry
+	svc.Set(ctx, "short", "z", time.Minute)
+	svc.Set(ctx, "short", "z", 0)
+	if got, _ := svc.Get(ctx, "short"); got != "z" {
+		t.Errorf("Get(short) = %q, want z", got)
+	}
+}
+
+func TestMemoryCacheClearPattern(t *testing.T) {
+	keys := []string{"users:1", "users:2", "users:10", "user:1", "posts:1", "a*b", "axb"}
+	tests := []struct {
+		pattern string
+		want    []string // keys removed
+	}{
+		{pattern: "users:*", want: []string{"users:1", "users:2", "users:10"}},
+		{pattern: "users:?", want: []string{"users:1", "users:2"}},
+		{pattern: "user[s]:1", want: []string{"users:1"}},
+		{pattern: "*:1", want: []string{"users:1", "user:1", "posts:1"}},
+		{pattern: `a\*b`, want: []string{"a*b"}},
+		{pattern: "a.b", want: nil},
+		{pattern: "*", want: keys},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			svc := NewMemoryCacheService(0)
+			defer svc.Close()
+			for _, key := range keys {
+				svc.Set(ctx, key, "v", 0)
+			}
+
+			deleted, err := svc.ClearPattern(ctx, tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != int64(len(tt.want)) {
+				t.Errorf("ClearPattern() = %d, want %d", deleted, len(tt.want))
+			}
+			removed := make(map[string]bool)
+			for _, key := range tt.want {
+				removed[key] = true
+			}
+			for _, key := range keys {
+				if got, _ := svc.Get(ctx, key); (got == "") != removed[key] {
+					t.Errorf("Get(%q) = %q after ClearPattern(%q)", key, got, tt.pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryCacheInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	svc := NewMemoryCacheService(2)
+	defer svc.Close()
+
+	svc.SetWithTags(ctx, "post:1", "a", 0, "posts", "user:1")
+	svc.SetWithTags(ctx, "post:2", "b", 0, "posts")
+
+	// Retagging replaces the old tags
+	svc.SetWithTags(ctx, "post:1", "a", 0, "drafts")
+	if deleted, _ := svc.InvalidateTags(ctx, "user:1"); deleted != 0 {
+		t.Errorf("InvalidateTags(user:1) = %d, want 0 after retagging", deleted)
+	}
+
+	if deleted, _ := svc.InvalidateTags(ctx, "posts", "drafts"); deleted != 2 {
+		t.Errorf("InvalidateTags() = %d, want 2", deleted)
+	}
+	if n, _ := svc.CountKeys(ctx); n != 0 {
+		t.Errorf("CountKeys() = %d, want 0", n)
+	}
+
+	// Evicted and removed entries leave no tag behind
+	svc.SetWithTags(ctx, "a", "1", 0, "t")
+	svc.SetWithTags(ctx, "b", "2", 0, "t")
+	svc.SetWithTags(ctx, "c", "3", 0, "u")
+	svc.Remove(ctx, "b")
+	if deleted, _ := svc.InvalidateTags(ctx, "t"); deleted != 0 {
+		t.Errorf("InvalidateTags(t) = %d, want 0", deleted)
+	}
+	if len(svc.tags) != 1 {
+		t.Errorf("tags = %v, want only u", svc.tags)
+	}
+}
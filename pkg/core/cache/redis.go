@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis modes selected by REDIS_MODE
+const (
+	RedisSingle   = "single"
+	RedisSentinel = "sentinel"
+	RedisCluster  = "cluster"
+)
+
+// NewRedisClient connects to Redis as configured by REDIS_MODE. REDIS_URI is a
+// comma separated list of host:port addresses or redis:// and rediss:// URLs;
+// sentinel mode expects the sentinels, cluster mode any seed nodes.
+func NewRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	opts, err := RedisOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.RedisMode {
+	case RedisCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case RedisSentinel:
+		return redis.NewFailoverClient(opts.Failover()), nil
+	default:
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+// RedisOptions builds client options from cfg. Credentials and a /db path in
+// the URL are used unless REDIS_USERNAME, REDIS_PASSWORD or REDIS_DB set them;
+// a rediss:// URL enables TLS like REDIS_TLS=true.
+func RedisOptions(cfg *config.Config) (*redis.UniversalOptions, error) {
+	opts := &redis.UniversalOptions{
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPassword,
+		DB:               cfg.RedisDB,
+		MasterName:       cfg.RedisMasterName,
+		SentinelPassword: cfg.RedisSentinelPass,
+	}
+
+	// Sentinels listen on 26379 by default
+	port := "6379"
+	if cfg.RedisMode == RedisSentinel {
+		port = "26379"
+	}
+
+	useTLS := cfg.RedisTLS
+	for _, part := range strings.Split(cfg.RedisURI, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "://") {
+			opts.Addrs = append(opts.Addrs, withDefaultPort(part, port))
+			continue
+		}
+
+		u, err := url.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse REDIS_URI: %w", err)
+		}
+		switch u.Scheme {
+		case "redis":
+		case "rediss":
+			useTLS = true
+		default:
+			return nil, fmt.Errorf("invalid REDIS_URI scheme %q, expected redis or rediss", u.Scheme)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid REDIS_URI %q: missing host", u.Redacted())
+		}
+		opts.Addrs = append(opts.Addrs, withDefaultPort(u.Host, port))
+
+		if u.User != nil {
+			if opts.Username == "" {
+				opts.Username = u.User.Username()
+			}
+			if password, ok := u.User.Password(); ok && opts.Password == "" {
+				opts.Password = password
+			}
+		}
+		if path := strings.Trim(u.Path, "/"); path != "" && opts.DB == 0 {
+			db, err := strconv.Atoi(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid REDIS_URI database %q: %w", path, err)
+			}
+			opts.DB = db
+		}
+	}
+
+	if len(opts.Addrs) == 0 {
+		opts.Addrs = []string{"localhost:6379"} // Default Redis server address
+	}
+	switch cfg.RedisMode {
+	case RedisCluster:
+		if opts.DB != 0 {
+			return nil, fmt.Errorf("redis cluster only supports database 0, got %d", opts.DB)
+		}
+	case RedisSentinel:
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+	default:
+		if len(opts.Addrs) > 1 {
+			return nil, fmt.Errorf("REDIS_URI lists %d addresses, set REDIS_MODE to sentinel or cluster", len(opts.Addrs))
+		}
+	}
+
+	if useTLS {
+		tlsConfig, err := redisTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	return opts, nil
+}
+
+// redisTLSConfig loads the CA and client certificate files; the server name
+// is taken from each node's address when dialing
+func redisTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.RedisTLSInsecure,
+	}
+	if cfg.RedisTLSCA != "" {
+		pem, err := os.ReadFile(cfg.RedisTLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read REDIS_TLS_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in REDIS_TLS_CA_FILE %s", cfg.RedisTLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.RedisTLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.RedisTLSCert, cfg.RedisTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func withDefaultPort(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(strings.Trim(addr, "[]"), port)
+	}
+	return addr
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// refreshTimeout bounds a shared load. Loads run detached from the context of
+// the request that started them, since other requests may be waiting on them
+// or, for a stale refresh, the request has already returned.
+const refreshTimeout = 30 * time.Second
+
+// loads shares in-flight loads; see flightKey
+var loads singleflight.Group
+
+// flightKey identifies a load by cache service as well as key, so Remember
+// calls against different services never share one
+func flightKey(svc CacheService, key string) string {
+	return fmt.Sprintf("%p|%s", svc, key)
+}
+
+// remembered is the envelope Remember stores: the JSON value, when it stops
+// being fresh, and whether it records a cached miss
+type remembered struct {
+	Value      json.RawMessage `json:"v,omitempty"`
+	FreshUntil int64           `json:"f"` // unix milliseconds
+	Negative   bool            `json:"n,omitempty"`
+}
+
+type rememberOptions struct {
+	stale       time.Duration
+	negativeTTL time.Duration
+	negative    error
+	tags        []string
+}
+
+// RememberOption customises Remember
+type RememberOption func(*rememberOptions)
+
+// WithStale keeps values for another d after ttl. A request in that window
+// gets the stale value at once while a single background load refreshes it.
+func WithStale(d time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.stale = d
+	}
+}
+
+// WithTags associates the cached value, or cached miss, with tags so
+// InvalidateTags can drop it when the underlying data changes
+func WithTags(tags ...string) RememberOption {
+	return func(o *rememberOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithNegative caches loader errors matching target (errors.Is) for ttl, so
+// repeated lookups of a missing record do not reach the database. Cached
+// misses return target.
+func WithNegative(target error, ttl time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.negative = target
+		o.negativeTTL = ttl
+	}
+}
+
+// Remember returns the value cached under key, or calls loader, caches its
+// result as JSON for ttl and returns it. Concurrent misses for the same key
+// in this process share one loader call; a caller whose ctx ends stops
+// waiting without failing the others. Cache failures are logged and fall
+// back to loader, so an unavailable cache never fails the request.
+//
+//	products, err := cache.Remember(ctx, app.Cache, "products:page:1", time.Minute,
+//		func(ctx context.Context) ([]Product, error) { return repo.List(ctx) },
+//		cache.WithStale(30*time.Second))
+func Remember[T any](ctx context.Context, svc CacheService, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...RememberOption) (T, error) {
+	var options rememberOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var zero T
+	shared := func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+		return load(loadCtx, svc, key, ttl, loader, options)
+	}
+
+	if entry, ok := lookup(ctx, svc, key); ok {
+		var value T
+		switch {
+		case entry.Negative:
+			// A cached miss only counts for callers that asked for negative
+			// caching; for the others it is a plain miss
+			if options.negative != nil {
+				return zero, options.negative
+			}
+		case json.Unmarshal(entry.Value, &value) == nil:
+			if time.Now().UnixMilli() > entry.FreshUntil {
+				// Serve the stale value; DoChan joins a refresh already in flight
+				// instead of starting a goroutine per stale hit
+				loads.DoChan(flightKey(svc, key), shared)
+			}
+			return value, nil
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-loads.DoChan(flightKey(svc, key), shared):
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		if result.Val == nil {
+			return zero, nil // a nil interface or pointer T
+		}
+		value, ok := result.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("cache key %s is being loaded as %T, not %s", key, result.Val, reflect.TypeFor[T]())
+		}
+		return value, nil
+	}
+}
+
+// lookup reads and decodes the envelope under key
+func lookup(ctx context.Context, svc CacheService, key string) (remembered, bool) {
+	if svc == nil {
+		return remembered{}, false
+	}
+	data, err := svc.Get(ctx, key)
+	if err != nil {
+		logger.Error("Cache read failed", zap.String("key", key), zap.Error(err))
+		return remembered{}, false
+	}
+	if data == "" {
+		return remembered{}, false
+	}
+	var entry remembered
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return remembered{}, false
+	}
+	return entry, true
+}
+
+// load calls loader and stores its result, or the miss when it is negative-cacheable
+func load[T any](ctx context.Context, svc CacheService, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), options rememberOptions) (T, error) {
+	value, err := loader(ctx)
+	if err != nil {
+		if options.negative != nil && errors.Is(err, options.negative) {
+			store(ctx, svc, key, remembered{Negative: true}, options.negativeTTL, options.tags)
+		}
+		return value, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+	entry := remembered{Value: data, FreshUntil: time.Now().Add(ttl).UnixMilli()}
+	store(ctx, svc, key, entry, ttl+options.stale, options.tags)
+	return value, nil
+}
+
+func store(ctx context.Context, svc CacheService, key string, entry remembered, expiration time.Duration, tags []string) {
+	if svc == nil || expiration <= 0 {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := svc.SetWithTags(ctx, key, string(data), expiration, tags...); err != nil {
+		logger.Error("Cache write failed", zap.String("key", key), zap.Error(err))
+	}
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("not found")
+
+// blockingLoader counts its calls and returns value once release is closed
+type blockingLoader struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	value   string
+	ctxErr  atomic.Value // the loader's ctx.Err() once released, as text
+}
+
+func newBlockingLoader(value string) *blockingLoader {
+	return &blockingLoader{started: make(chan struct{}, 1), release: make(chan struct{}), value: value}
+}
+
+func (l *blockingLoader) load(ctx context.Context) (string, error) {
+	l.calls.Add(1)
+	l.started <- struct{}{}
+	<-l.release
+	l.ctxErr.Store(fmt.Sprint(ctx.Err()))
+	return l.value, nil
+}
+
+// storeEnvelope writes a Remember envelope directly
+func storeEnvelope(t *testing.T, svc CacheService, key string, entry remembered) {
+	t.Helper()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Set(context.Background(), key, string(data), time.Minute)
+}
+
+func TestRememberSharesOneLoad(t *testing.T) {
+	svc := NewMemoryCacheService(0)
+	defer svc.Close()
+	loader := newBlockingLoader("v")
+
+	// The first caller gives up while the load is running
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := Remember(firstCtx, svc, "k", time.Minute, loader.load)
+		firstErr <- err
+	}()
+	<-loader.started
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := Remember(context.Background(), svc, "k", time.Minute, loader.load)
+			if err != nil {
+				t.Errorf("Remember() error = %v", err)
+			}
+			results[i] = value
+		}(i)
+	}
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Remember() error = %v, want context.Canceled", err)
+	}
+	close(loader.release)
+	wg.Wait()
+
+	if n := loader.calls.Load(); n != 1 {
+		t.Errorf("loader calls = %d, want 1", n)
+	}
+	for i, value := range results {
+		if value != "v" {
+			t.Errorf("results[%d] = %q, want v", i, value)
+		}
+	}
+	if err := loader.ctxErr.Load(); err != "<nil>" {
+		t.Errorf("loader ctx error = %v, want the load to outlive the cancelled caller", err)
+	}
+}
+
+func TestRememberServesStaleAndRefreshesOnce(t *testing.T) {
+	svc := NewMemoryCacheService(0)
+	defer svc.Close()
+	old, _ := json.Marshal("old")
+	storeEnvelope(t, svc, "k", remembered{Value: old, FreshUntil: time.Now().Add(-time.Second).UnixMilli()})
+	loader := newBlockingLoader("new")
+
+	for i := 0; i < 3; i++ {
+		value, err := Remember(context.Background(), svc, "k", time.Minute, loader.load, WithStale(time.Minute))
+		if err != nil || value != "old" {
+			t.Fatalf("Remember() = %q, %v, want the stale value", value, err)
+		}
+		if i == 0 {
+			<-loader.started
+		}
+	}
+	close(loader.release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, ok := lookup(context.Background(), svc, "k")
+		if ok && string(entry.Value) == `"new"` && entry.FreshUntil > time.Now().UnixMilli() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cached entry = %+v, want the refreshed value", entry)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := loader.calls.Load(); n != 1 {
+		t.Errorf("loader calls = %d, want one refresh for every stale hit", n)
+	}
+}
+
+func TestRememberNegative(t *testing.T) {
+	svc := NewMemoryCacheService(0)
+	defer svc.Close()
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	missing := func(ctx context.Context) (*string, error) {
+		calls.Add(1)
+		return nil, errNotFound
+	}
+	for i := 0; i < 2; i++ {
+		value, err := Remember(ctx, svc, "k", time.Minute, missing, WithNegative(errNotFound, time.Minute))
+		if !errors.Is(err, errNotFound) || value != nil {
+			t.Fatalf("Remember() = %v, %v, want errNotFound", value, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader calls = %d, want the miss to be cached", n)
+	}
+
+	// Without WithNegative the cached miss is ignored rather than returned as (nil, nil)
+	found := "found"
+	value, err := Remember(ctx, svc, "k", time.Minute, func(ctx context.Context) (*string, error) {
+		calls.Add(1)
+		return &found, nil
+	})
+	if err != nil || value == nil || *value != found {
+		t.Errorf("Remember() = %v, %v, want the loaded value", value, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("loader calls = %d, want 2", n)
+	}
+
+	// Errors that do not match the target are not cached
+	other := errors.New("boom")
+	for i := 0; i < 2; i++ {
+		Remember(ctx, svc, "other", time.Minute, func(ctx context.Context) (*string, error) {
+			calls.Add(1)
+			return nil, other
+		}, WithNegative(errNotFound, time.Minute))
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("loader calls = %d, want 4", n)
+	}
+}
+
+func TestRememberSeparatesServicesAndTypes(t *testing.T) {
+	first, second := NewMemoryCacheService(0), NewMemoryCacheService(0)
+	defer first.Close()
+	defer second.Close()
+
+	// Same key on two services: each gets its own load
+	loader := newBlockingLoader("first")
+	done := make(chan string, 1)
+	go func() {
+		value, _ := Remember(context.Background(), first, "k", time.Minute, loader.load)
+		done <- value
+	}()
+	<-loader.started
+
+	var calls atomic.Int32
+	value, err := Remember(context.Background(), second, "k", time.Minute, func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "second", nil
+	})
+	if err != nil || value != "second" || calls.Load() != 1 {
+		t.Errorf("Remember() on another service = %q, %v after %d loads, want its own load", value, err, calls.Load())
+	}
+
+	// Same key and service, another type: an error rather than a zero value
+	typeErr := make(chan error, 1)
+	go func() {
+		_, err := Remember(context.Background(), first, "k", time.Minute, func(ctx context.Context) (int, error) {
+			return 1, nil
+		})
+		typeErr <- err
+	}()
+	// Wait until the int call has joined the flight, i.e. it is blocked
+	time.Sleep(50 * time.Millisecond)
+	close(loader.release)
+
+	if got := <-done; got != "first" {
+		t.Errorf("Remember() = %q, want first", got)
+	}
+	if err := <-typeErr; err == nil {
+		t.Error("Remember() with a mismatched type error = nil")
+	}
+}
@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// InvalidationChannel is the Redis pub/sub channel tiered caches use to evict
+// each other's L1 entries
+const InvalidationChannel = "rootx:cache:invalidate"
+
+// DefaultL1TTL bounds how long an L1 entry may outlive a change it missed,
+// e.g. while the pub/sub connection was down
+const DefaultL1TTL = 5 * time.Second
+
+// invalidation is the message published on InvalidationChannel
+type invalidation struct {
+	Origin  string   `json:"origin"`
+	Key     string   `json:"key,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	Keys    []string `json:"keys,omitempty"`
+}
+
+// TieredCacheService keeps a small in-process L1 in front of Redis (L2).
+// Reads are served from L1 when possible; writes go to Redis and are
+// broadcast so every instance drops its L1 copy of the changed keys.
+type TieredCacheService struct {
+	l1     *MemoryCacheService
+	l2     *RedisCacheService
+	ttl    time.Duration
+	origin string
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// NewTieredCacheService wraps l2 with an L1 of size entries kept for at most
+// ttl (DefaultL1TTL when ttl <= 0), and subscribes to invalidations
+func NewTieredCacheService(ctx context.Context, l2 *RedisCacheService, size int, ttl time.Duration) (*TieredCacheService, error) {
+	if ttl <= 0 {
+		ttl = DefaultL1TTL
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	pubsub := l2.client.Subscribe(ctx, InvalidationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to cache invalidations: %w", err)
+	}
+
+	svc := &TieredCacheService{
+		l1:     NewMemoryCacheService(size),
+		l2:     l2,
+		ttl:    ttl,
+		origin: hex.EncodeToString(id),
+		pubsub: pubsub,
+		done:   make(chan struct{}),
+	}
+	go svc.listen()
+	return svc, nil
+}
+
+// Get retrieves value from L1, falling back to Redis and filling L1 on a hit
+func (svc *TieredCacheService) Get(ctx context.Context, key string) (string, error) {
+	if value, _ := svc.l1.Get(ctx, key); value != "" {
+		return value, nil
+	}
+	value, err := svc.l2.Get(ctx, key)
+	if err != nil || value == "" {
+		return value, err
+	}
+	svc.l1.Set(ctx, key, value, svc.ttl)
+	return value, nil
+}
+
+// Set writes value to Redis and L1 and evicts the key on other instances
+func (svc *TieredCacheService) Set(ctx context.Context, key, value string, expiration time.Duration) error {
+	if err := svc.l2.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	svc.l1.Set(ctx, key, value, svc.l1TTL(expiration))
+	return svc.publish(ctx, invalidation{Key: key})
+}
+
+// SetWithTags writes a tagged value to Redis and L1 and evicts the key on other instances
+func (svc *TieredCacheService) SetWithTags(ctx context.Context, key, value string, expiration time.Duration, tags ...string) error {
+	if err := svc.l2.SetWithTags(ctx, key, value, expiration, tags...); err != nil {
+		return err
+	}
+	svc.l1.SetWithTags(ctx, key, value, svc.l1TTL(expiration), tags...)
+	return svc.publish(ctx, invalidation{Key: key})
+}
+
+// InvalidateTags deletes the tagged keys from Redis and from the L1 of every
+// instance. The deleted keys are broadcast rather than the tags, since L1
+// entries filled from Redis do not carry their tags.
+func (svc *TieredCacheService) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	svc.l1.InvalidateTags(ctx, tags...)
+	deleted, keys, err := svc.l2.invalidateTags(ctx, tags...)
+	for _, key := range keys {
+		svc.l1.Remove(ctx, key)
+	}
+	if err != nil || len(keys) == 0 {
+		return deleted, err
+	}
+	return deleted, svc.publish(ctx, invalidation{Keys: keys})
+}
+
+// Remove deletes key everywhere
+func (svc *TieredCacheService) Remove(ctx context.Context, key string) error {
+	svc.l1.Remove(ctx, key)
+	if err := svc.l2.Remove(ctx, key); err != nil {
+		return err
+	}
+	return svc.publish(ctx, invalidation{Key: key})
+}
+
+// CountKeys counts the keys in Redis
+func (svc *TieredCacheService) CountKeys(ctx context.Context) (int64, error) {
+	return svc.l2.CountKeys(ctx)
+}
+
+// ClearPattern deletes the matching keys from Redis and from the L1 of every instance
+func (svc *TieredCacheService) ClearPattern(ctx context.Context, pattern string) (int64, error) {
+	svc.l1.ClearPattern(ctx, pattern)
+	deleted, err := svc.l2.ClearPattern(ctx, pattern)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, svc.publish(ctx, invalidation{Pattern: pattern})
+}
+
+// Close stops listening for invalidations and closes both tiers
+func (svc *TieredCacheService) Close() error {
+	err := svc.pubsub.Close()
+	<-svc.done
+	svc.l1.Close()
+	if closeErr := svc.l2.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// l1TTL caps an entry's L1 lifetime at the service TTL
+func (svc *TieredCacheService) l1TTL(expiration time.Duration) time.Duration {
+	if expiration > 0 && expiration < svc.ttl {
+		return expiration
+	}
+	return svc.ttl
+}
+
+func (svc *TieredCacheService) publish(ctx context.Context, msg invalidation) error {
+	msg.Origin = svc.origin
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := svc.l2.client.Publish(ctx, InvalidationChannel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+// listen applies invalidations published by other instances to L1
+func (svc *TieredCacheService) listen() {
+	defer close(svc.done)
+
+	ctx := context.Background()
+	for message := range svc.pubsub.Channel() {
+		var msg invalidation
+		if err := json.Unmarshal([]byte(message.Payload), &msg); err != nil {
+			logger.Error("Invalid cache invalidation message", zap.Error(err))
+			continue
+		}
+		if msg.Origin == svc.origin {
+			continue
+		}
+		if msg.Key != "" {
+			svc.l1.Remove(ctx, msg.Key)
+		}
+		for _, key := range msg.Keys {
+			svc.l1.Remove(ctx, key)
+		}
+		if msg.Pattern != "" {
+			if _, err := svc.l1.ClearPattern(ctx, msg.Pattern); err != nil {
+				logger.Error("Failed to apply cache invalidation", zap.String("pattern", msg.Pattern), zap.Error(err))
+			}
+		}
+	}
+}
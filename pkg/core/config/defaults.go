@@ -0,0 +1,56 @@
+package config
+
+// defaults are the lowest-precedence values, used for keys no source sets
+var defaults = map[string]any{
+	"APP_ENV":                   "development",
+	"APP_PORT":                  8080,
+	"LOG_LEVEL":                 "info",
+	"DB_TYPE":                   "postgres",
+	"DB_HOST":                   "localhost",
+	"DB_PORT":                   5432,
+	"DB_SSLMODE":                "disable",
+	"DB_MAX_IDLE_CONNS":         10,
+	"DB_MAX_CONN_LIFETIME":      "10m",
+	"MAX_CONNS":                 25,
+	"MIN_CONNS":                 0,
+	"DB_REPLICA_CHECK_INTERVAL": 5,
+	"REDIS_EXP":                 3600,
+	"REDIS_URI":                 "redis://localhost:6379",
+	"REDIS_MODE":                "single",
+	"CACHE_MEMORY_SIZE":         10000,
+	"CACHE_L1_SIZE":             1000,
+	"CACHE_L1_TTL":              "5s",
+	"RATE_LIMIT":                500,
+	"CORS_ALLOWED_ORIGINS":      "*",
+	"CORS_ALLOWED_METHODS":      "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+	"CORS_ALLOWED_HEADERS":      "Content-Type,Authorization,If-Match",
+	"RATE_LIMIT_DURATION":       "1m",
+	"JWT_EXPIRATION":            "24h",
+	"STORAGE_DISK":              "local",
+	"STORAGE_PATH":              "storage",
+	"OTP_EXPIRATION":            1,
+	"OTP_LENGTH":                6,
+	"OTP_RESEND_DURATION":       300,
+	"READ_TIMEOUT":              30,
+	"WRITE_TIMEOUT":             30,
+	"IDLE_TIMEOUT":              120,
+	"MAX_HEADER_BYTES":          1 << 20,
+	"SHUTDOWN_TIMEOUT":          30,
+}
+
+// envDefaults override defaults for one APP_ENV; files and the environment
+// still take precedence
+var envDefaults = map[string]map[string]any{
+	EnvDevelopment: {
+		"LOG_LEVEL": "debug",
+	},
+	EnvTest: {
+		"LOG_LEVEL":          "warn",
+		"RATE_LIMIT_ENABLED": false,
+	},
+	EnvProduction: {
+		"LOG_LEVEL":          "info",
+		"DB_SSLMODE":         "require",
+		"RATE_LIMIT_ENABLED": true,
+	},
+}
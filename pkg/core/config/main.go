@@ -1,93 +1,304 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
 	BuildVersion      string        `mapstructure:"VERSION"`
-	AppEnv            string        `mapstructure:"APP_ENV"`
-	AppPort           int           `mapstructure:"APP_PORT"`
+	AppEnv            string        `mapstructure:"APP_ENV" validate:"required,oneof=development test staging production"`
+	LogLevel          string        `mapstructure:"LOG_LEVEL" validate:"required,oneof=debug info warn error"`
+	ConfigWatch       bool          `mapstructure:"CONFIG_WATCH"`
+	AppPort           int           `mapstructure:"APP_PORT" validate:"required,min=1,max=65535"`
 	Domain            string        `mapstructure:"DOMAIN"`
-	DBType            string        `mapstructure:"DB_TYPE"`
-	DBHost            string        `mapstructure:"DB_HOST"`
-	DBPort            int           `mapstructure:"DB_PORT"`
-	DBName            string        `mapstructure:"DB_NAME"`
-	DBUser            string        `mapstructure:"DB_USER"`
-	DBPassword        string        `mapstructure:"DB_PASSWORD"`
-	DBSSLMode         string        `mapstructure:"DB_SSLMODE"`
-	DBMaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS"`
-	DBMaxConnLifetime time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME"`
-	MaxConns          int           `mapstructure:"MAX_CONNS"`
-	MinConns          int           `mapstructure:"MIN_CONNS"`
+	DBType            string        `mapstructure:"DB_TYPE" validate:"required,oneof=postgres mysql"`
+	DBHost            string        `mapstructure:"DB_HOST" validate:"required"`
+	DBPort            int           `mapstructure:"DB_PORT" validate:"required,min=1,max=65535"`
+	DBName            string        `mapstructure:"DB_NAME" validate:"required"`
+	DBUser            string        `mapstructure:"DB_USER" validate:"required"`
+	DBPassword        string        `mapstructure:"DB_PASSWORD" secret:"true"`
+	DBSSLMode         string        `mapstructure:"DB_SSLMODE" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	DBMaxIdleConns    int           `mapstructure:"DB_MAX_IDLE_CONNS" validate:"min=0"`
+	DBMaxConnLifetime time.Duration `mapstructure:"DB_MAX_CONN_LIFETIME" validate:"min=0"`
+	MaxConns          int           `mapstructure:"MAX_CONNS" validate:"min=1"`
+	MinConns          int           `mapstructure:"MIN_CONNS" validate:"min=0,ltefield=MaxConns"`
+	DBReplicas        []string      `mapstructure:"DB_REPLICAS"`
+	DBReplicaInterval int           `mapstructure:"DB_REPLICA_CHECK_INTERVAL" validate:"min=0"`
+	CorsOrigins       []string      `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	CorsMethods       []string      `mapstructure:"CORS_ALLOWED_METHODS"`
+	CorsHeaders       []string      `mapstructure:"CORS_ALLOWED_HEADERS"`
 	Migrate           bool          `mapstructure:"MIGRATE"`
 	Seed              bool          `mapstructure:"SEED"`
-	RedisExp          int           `mapstructure:"REDIS_EXP"`
+	RedisExp          int           `mapstructure:"REDIS_EXP" validate:"min=0"`
+	RedisMode         string        `mapstructure:"REDIS_MODE" validate:"required,oneof=single sentinel cluster"`
 	RedisURI          string        `mapstructure:"REDIS_URI"`
-	RedisPassword     string        `mapstructure:"REDIS_PASSWORD"`
-	RedisDB           int           `mapstructure:"REDIS_DB"`
+	RedisUsername     string        `mapstructure:"REDIS_USERNAME"`
+	RedisPassword     string        `mapstructure:"REDIS_PASSWORD" secret:"true"`
+	RedisDB           int           `mapstructure:"REDIS_DB" validate:"min=0,max=15"`
+	RedisMasterName   string        `mapstructure:"REDIS_MASTER_NAME" validate:"required_if=RedisMode sentinel"`
+	RedisSentinelPass string        `mapstructure:"REDIS_SENTINEL_PASSWORD" secret:"true"`
+	RedisTLS          bool          `mapstructure:"REDIS_TLS"`
+	RedisTLSCA        string        `mapstructure:"REDIS_TLS_CA_FILE"`
+	RedisTLSCert      string        `mapstructure:"REDIS_TLS_CERT_FILE" validate:"required_with=RedisTLSKey"`
+	RedisTLSKey       string        `mapstructure:"REDIS_TLS_KEY_FILE" validate:"required_with=RedisTLSCert"`
+	RedisTLSInsecure  bool          `mapstructure:"REDIS_TLS_INSECURE"`
 	IsRedis           bool          `mapstructure:"IS_REDIS"`
+	CacheMemorySize   int           `mapstructure:"CACHE_MEMORY_SIZE" validate:"min=0"`
+	CacheL1           bool          `mapstructure:"CACHE_L1"`
+	CacheL1Size       int           `mapstructure:"CACHE_L1_SIZE" validate:"min=0"`
+	CacheL1TTL        time.Duration `mapstructure:"CACHE_L1_TTL" validate:"min=0"`
 	RateLimitEnabled  bool          `mapstructure:"RATE_LIMIT_ENABLED"`
-	RateLimit         int           `mapstructure:"RATE_LIMIT"`
-	RateLimitDuration time.Duration `mapstructure:"RATE_LIMIT_DURATION"`
-	JwtSecretKey      string        `mapstructure:"JWT_SECRET_KEY"`
-	JwtExpiration     time.Duration `mapstructure:"JWT_EXPIRATION"`
-	StorageDisk       string        `mapstructure:"STORAGE_DISK"`
+	RateLimit         int           `mapstructure:"RATE_LIMIT" validate:"min=0"`
+	RateLimitDuration time.Duration `mapstructure:"RATE_LIMIT_DURATION" validate:"min=0"`
+	JwtSecretKey      string        `mapstructure:"JWT_SECRET_KEY" validate:"required" secret:"true"`
+	JwtExpiration     time.Duration `mapstructure:"JWT_EXPIRATION" validate:"min=0"`
+	CursorSecret      string        `mapstructure:"CURSOR_SECRET" secret:"true"`
+	StorageDisk       string        `mapstructure:"STORAGE_DISK" validate:"required,oneof=local s3"`
 	StoragePath       string        `mapstructure:"STORAGE_PATH"`
 	AwsRegion         string        `mapstructure:"AWS_REGION"`
-	AwsAccessKey      string        `mapstructure:"AWS_ACCESS_KEY"`
-	AwsSecretKey      string        `mapstructure:"AWS_SECRET_KEY"`
+	AwsAccessKey      string        `mapstructure:"AWS_ACCESS_KEY" secret:"true"`
+	AwsSecretKey      string        `mapstructure:"AWS_SECRET_KEY" secret:"true"`
 	AwsBucket         string        `mapstructure:"AWS_BUCKET"`
 	AwsEndpoint       string        `mapstructure:"AWS_ENDPOINT"`
 	OtpExpiration     int           `mapstructure:"OTP_EXPIRATION"`
-	OtpLength         int           `mapstructure:"OTP_LENGTH"`
+	OtpLength         int           `mapstructure:"OTP_LENGTH" validate:"min=4,max=12"`
 	OtpResendDuration int           `mapstructure:"OTP_RESEND_DURATION"`
-	ReadTimeout       int           `mapstructure:"READ_TIMEOUT"`
-	WriteTimeout      int           `mapstructure:"WRITE_TIMEOUT"`
-	IdleTimeout       int           `mapstructure:"IDLE_TIMEOUT"`
-	MaxHeaderBytes    int           `mapstructure:"MAX_HEADER_BYTES"`
+	ReadTimeout       int           `mapstructure:"READ_TIMEOUT" validate:"min=0"`
+	WriteTimeout      int           `mapstructure:"WRITE_TIMEOUT" validate:"min=0"`
+	IdleTimeout       int           `mapstructure:"IDLE_TIMEOUT" validate:"min=0"`
+	MaxHeaderBytes    int           `mapstructure:"MAX_HEADER_BYTES" validate:"min=0"`
+	ShutdownTimeout   int           `mapstructure:"SHUTDOWN_TIMEOUT" validate:"min=0"`
+	ShutdownDrainWait int           `mapstructure:"SHUTDOWN_DRAIN_WAIT" validate:"min=0"`
+
+	sections map[string]any // custom sections by prefix, see Register
 }
 
 var (
+	// Deprecated: GlobalConfig is the result of the last Load and is not
+	// updated by Reload; use GetConfig for the current snapshot.
 	GlobalConfig *Config
 	configMutex  sync.Mutex
+	configFlags  *pflag.FlagSet
+	configSecret []SecretProvider
+)
+
+// Environments selected by APP_ENV
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvStaging     = "staging"
+	EnvProduction  = "production"
 )
 
-// LoadConfig loads the configuration from the environment and config file
+// DefaultFiles are the config files LoadConfig reads when they exist
+var DefaultFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// Options selects the sources Load reads. Later sources override earlier ones:
+//
+//	defaults < Files (in order) < EnvFile < EnvFile.<APP_ENV> < secrets < environment variables < Flags
+//
+// Secrets come from Options.Secrets, the providers configured by SECRETS_DIR,
+// SECRETS_FILE and SECRETS_VAULT, and KEY_FILE variables (DB_PASSWORD_FILE=/run/secrets/db).
+// APP_ENV (or Options.Env) also selects environment-specific defaults, e.g.
+// LOG_LEVEL is debug in development and info in production.
+// Missing files are skipped, so a deployment can configure everything through
+// the environment. Keys are the env names everywhere (APP_PORT: 8080 in YAML,
+// --app-port for flags).
+type Options struct {
+	Files   []string       // YAML or TOML files, DefaultFiles when nil
+	EnvFile string         // dotenv file, ".env" when empty
+	Flags   *pflag.FlagSet // flags named after keys, e.g. --db-host; SetFlags' set when nil
+	Env     string         // forces APP_ENV, e.g. to inspect the production config locally
+	Secrets []SecretProvider
+}
+
+// SetFlags makes LoadConfig bind fs, typically a cobra command's flag set
+func SetFlags(fs *pflag.FlagSet) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configFlags = fs
+}
+
+// UseSecrets adds providers consulted by LoadConfig
+func UseSecrets(providers ...SecretProvider) {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	configSecret = append(configSecret, providers...)
+}
+
+// LoadConfig loads the configuration from the default sources
 func LoadConfig() (*Config, error) {
+	return Load(Options{})
+}
+
+// Load reads, merges and validates the configuration from opts, and makes it
+// the current snapshot returned by GetConfig. Validation problems are reported
+// together in one error. Reload and Watch reuse opts.
+func Load(opts Options) (*Config, error) {
 	// Lock the mutex to ensure thread safety during configuration loading
 	configMutex.Lock()
 	defer configMutex.Unlock()
 
-	// Initialize viper to read from .env file and environment variables
-	viper.SetConfigFile(".env")
-	viper.AutomaticEnv()
+	cfg, paths, err := load(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the global configuration variable
+	GlobalConfig = cfg
+	current.Store(cfg)
+	lastOptions, watchPaths = opts, paths
+
+	return cfg, nil
+}
+
+// load builds a Config from opts and returns it with the paths it read (or
+// would have read, had they existed), for Watch
+func load(opts Options) (*Config, []string, error) {
+	if opts.Flags == nil {
+		opts.Flags = configFlags
+	}
+	secrets := append(append([]SecretProvider(nil), configSecret...), opts.Secrets...)
+
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	var paths []string
+	merge := func(file, configType string) error {
+		paths = append(paths, file)
+		return mergeFile(v, file, configType)
+	}
+
+	files := opts.Files
+	if files == nil {
+		files = DefaultFiles
+	}
+	for _, file := range files {
+		if err := merge(file, ""); err != nil {
+			return nil, nil, err
+		}
+	}
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	if err := merge(envFile, "env"); err != nil {
+		return nil, nil, err
+	}
+
+	// Bind every key explicitly: AutomaticEnv alone only sees keys some other
+	// source already mentioned
+	configKeys := keys(reflect.TypeOf(Config{}))
+	for _, s := range sections {
+		s.setDefaults(v)
+		configKeys = append(configKeys, s.keys()...)
+	}
+	for _, key := range append(configKeys, "SECRETS_DIR", "SECRETS_FILE", "SECRETS_VAULT") {
+		if err := v.BindEnv(key); err != nil {
+			return nil, nil, fmt.Errorf("failed to bind %s: %w", key, err)
+		}
+		if opts.Flags == nil {
+			continue
+		}
+		if flag := opts.Flags.Lookup(flagName(key)); flag != nil {
+			if err := v.BindPFlag(key, flag); err != nil {
+				return nil, nil, fmt.Errorf("failed to bind flag --%s: %w", flag.Name, err)
+			}
+		}
+	}
 
-	// Read the configuration file
-	if err := viper.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	// Pick the environment, then layer its defaults and .env.<env> overlay
+	env := opts.Env
+	if env != "" {
+		v.Set("APP_ENV", env)
+	} else {
+		env = strings.TrimSpace(v.GetString("APP_ENV"))
+	}
+	for key, value := range envDefaults[env] {
+		v.SetDefault(key, value)
+	}
+	if env != "" {
+		if err := merge(envFile+"."+env, "env"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Secrets override every file, so credentials can stay out of .env
+	providers, err := defaultProviders(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mergeSecrets(v, configKeys, append(providers, secrets...)); err != nil {
+		return nil, nil, fmt.Errorf("failed to load secrets: %w", err)
 	}
 
 	// Unmarshal the configuration into a Config struct
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
 	// Validate configuration values
 	if err := stringTrim(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to trim config values: %w", err)
+		return nil, nil, fmt.Errorf("failed to trim config values: %w", err)
 	}
+	problems := validate(&cfg, "")
 
-	// Set the global configuration variable
-	GlobalConfig = &cfg
+	// Custom sections go through the same sources and validation
+	cfg.sections = make(map[string]any, len(sections))
+	for _, s := range sections {
+		value, errs := s.decode(v)
+		cfg.sections[s.prefix] = value
+		problems = append(problems, errs...)
+	}
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
+	}
 
-	return &cfg, nil
+	return &cfg, append(paths, secretPaths(v, configKeys)...), nil
+}
+
+// mergeFile merges file into v if it exists; configType overrides the type
+// taken from the extension (dotenv files usually have none)
+func mergeFile(v *viper.Viper, file, configType string) error {
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	v.SetConfigFile(file)
+	v.SetConfigType(cmp.Or(configType, strings.TrimPrefix(filepath.Ext(file), ".")))
+	if err := v.MergeInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// keys returns the mapstructure names of typ's fields
+func keys(typ reflect.Type) []string {
+	names := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		if name := typ.Field(i).Tag.Get("mapstructure"); name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// flagName maps a key to its flag, APP_PORT to app-port
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
 }
 
 func stringTrim(cfg *Config) error {
@@ -99,6 +310,7 @@ func stringTrim(cfg *Config) error {
 	cfg.DBPassword = strings.TrimSpace(cfg.DBPassword)
 	cfg.DBSSLMode = strings.TrimSpace(cfg.DBSSLMode)
 	cfg.JwtSecretKey = strings.TrimSpace(cfg.JwtSecretKey)
+	cfg.CursorSecret = strings.TrimSpace(cfg.CursorSecret)
 	cfg.StorageDisk = strings.TrimSpace(cfg.StorageDisk)
 	cfg.StoragePath = strings.TrimSpace(cfg.StoragePath)
 	cfg.AwsRegion = strings.TrimSpace(cfg.AwsRegion)
@@ -110,6 +322,21 @@ func stringTrim(cfg *Config) error {
 	return nil
 }
 
+// GetConfig returns the current configuration snapshot. Snapshots are
+// replaced, never modified, so treat the result as read-only.
 func GetConfig() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
 	return GlobalConfig
 }
+
+// IsProduction reports whether APP_ENV is production
+func (c *Config) IsProduction() bool {
+	return c.AppEnv == EnvProduction
+}
+
+// IsDevelopment reports whether APP_ENV is development
+func (c *Config) IsDevelopment() bool {
+	return c.AppEnv == EnvDevelopment
+}
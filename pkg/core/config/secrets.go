@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SecretProvider supplies configuration values kept outside .env, such as
+// mounted secret files or an encrypted vault. Load asks providers for every
+// key; their values override files and .env but not environment variables.
+type SecretProvider interface {
+	// Secret returns the value for key and whether the provider has one
+	Secret(ctx context.Context, key string) (string, bool, error)
+}
+
+// DirProvider reads one secret per file from a directory, the layout of Docker
+// and Kubernetes secret mounts. The file is named after the key, in upper or
+// lower case (DB_PASSWORD or db_password); a trailing newline is dropped.
+type DirProvider struct {
+	Dir string
+}
+
+// NewDirProvider returns a provider reading from dir
+func NewDirProvider(dir string) *DirProvider {
+	return &DirProvider{Dir: dir}
+}
+
+// Secret implements SecretProvider
+func (p *DirProvider) Secret(ctx context.Context, key string) (string, bool, error) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		value, err := readSecretFile(filepath.Join(p.Dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// FileProvider reads secrets from a single KEY=VALUE file kept outside the
+// project, e.g. /etc/myapp/secrets.env
+type FileProvider struct {
+	values map[string]string
+}
+
+// NewFileProvider parses path in dotenv format
+func NewFileProvider(path string) (*FileProvider, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("env")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read secrets file %s: %w", path, err)
+	}
+	values := make(map[string]string)
+	for _, key := range v.AllKeys() {
+		values[strings.ToUpper(key)] = v.GetString(key)
+	}
+	return &FileProvider{values: values}, nil
+}
+
+// Secret implements SecretProvider
+func (p *FileProvider) Secret(ctx context.Context, key string) (string, bool, error) {
+	value, ok := p.values[key]
+	return value, ok, nil
+}
+
+// readSecretFile returns the content of a secret file without its trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// defaultProviders builds the providers configured through SECRETS_DIR,
+// SECRETS_FILE and SECRETS_VAULT (unlocked by SECRETS_VAULT_KEY)
+func defaultProviders(v *viper.Viper) ([]SecretProvider, error) {
+	var providers []SecretProvider
+	if dir := v.GetString("SECRETS_DIR"); dir != "" {
+		providers = append(providers, NewDirProvider(dir))
+	}
+	if file := v.GetString("SECRETS_FILE"); file != "" {
+		provider, err := NewFileProvider(file)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	if path := v.GetString("SECRETS_VAULT"); path != "" {
+		vault, err := OpenVault(path, os.Getenv("SECRETS_VAULT_KEY"))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, vault)
+	}
+	return providers, nil
+}
+
+// secretPaths returns the files and directories secrets were read from
+func secretPaths(v *viper.Viper, keys []string) []string {
+	var paths []string
+	for _, key := range []string{"SECRETS_DIR", "SECRETS_FILE", "SECRETS_VAULT"} {
+		if path := v.GetString(key); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	for _, key := range keys {
+		if path, ok := os.LookupEnv(key + "_FILE"); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// mergeSecrets resolves keys from providers (later providers win) and then from
+// KEY_FILE environment variables, which point at a file holding the value.
+// Both are merged above the config files so only real environment variables
+// and flags override them.
+func mergeSecrets(v *viper.Viper, keys []string, providers []SecretProvider) error {
+	ctx := context.Background()
+	values := make(map[string]any)
+	var errs []error
+	for _, key := range keys {
+		for _, provider := range providers {
+			value, ok, err := provider.Secret(ctx, key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read secret %s: %w", key, err))
+				continue
+			}
+			if ok {
+				values[key] = value
+			}
+		}
+
+		path, ok := os.LookupEnv(key + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			errs = append(errs, fmt.Errorf("both %s and %s_FILE are set", key, key))
+			continue
+		}
+		value, err := readSecretFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s_FILE: %w", key, err))
+			continue
+		}
+		values[key] = value
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return v.MergeConfigMap(values)
+}
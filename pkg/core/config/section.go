@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// section is a registered custom configuration struct
+type section struct {
+	prefix string
+	typ    reflect.Type
+}
+
+var sections []*section // guarded by configMutex
+
+// Section is the handle returned by Register
+type Section[T any] struct {
+	prefix string
+}
+
+// Register adds a typed configuration section loaded with the same sources,
+// secrets and validation as Config. Field keys come from `mapstructure` tags
+// and are prefixed, so with prefix PAYMENT
+//
+//	type PaymentConfig struct {
+//		APIKey  string        `mapstructure:"API_KEY" validate:"required" secret:"true"`
+//		Timeout time.Duration `mapstructure:"TIMEOUT" default:"10s"`
+//	}
+//
+// reads PAYMENT_API_KEY and PAYMENT_TIMEOUT. Register before LoadConfig or
+// StartApp, typically in a package var. Registering a prefix twice, or one
+// whose keys collide with Config or another section (DB with HOST), panics.
+func Register[T any](prefix string) *Section[T] {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config section %s must be a struct, got %s", prefix, typ))
+	}
+	prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_"))
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
+	for _, s := range sections {
+		if s.prefix == prefix {
+			panic(fmt.Sprintf("config section %s registered twice", prefix))
+		}
+	}
+	added := &section{prefix: prefix, typ: typ}
+	if key, ok := collision(added); ok {
+		panic(fmt.Sprintf("config section %s key %s is already used", prefix, key))
+	}
+	sections = append(sections, added)
+	return &Section[T]{prefix: prefix}
+}
+
+// Get returns the section from the current configuration snapshot, or nil
+// before the configuration is loaded
+func (s *Section[T]) Get() *T {
+	return s.From(GetConfig())
+}
+
+// From returns the section stored in cfg
+func (s *Section[T]) From(cfg *Config) *T {
+	if cfg == nil {
+		return nil
+	}
+	value, _ := cfg.sections[s.prefix].(*T)
+	return value
+}
+
+// Section returns the loaded section registered under prefix, or nil
+func (c *Config) Section(prefix string) any {
+	if c == nil {
+		return nil
+	}
+	return c.sections[strings.ToUpper(strings.TrimSuffix(prefix, "_"))]
+}
+
+// key returns the full key of a section field, PAYMENT_API_KEY for API_KEY
+func (s *section) key(field string) string {
+	return s.prefix + "_" + field
+}
+
+// collision returns the first key of s that Config or a registered section
+// already reads
+func collision(s *section) (string, bool) {
+	taken := make(map[string]bool)
+	for _, key := range keys(reflect.TypeOf(Config{})) {
+		taken[key] = true
+	}
+	for _, other := range sections {
+		for _, key := range other.keys() {
+			taken[key] = true
+		}
+	}
+	for _, key := range s.keys() {
+		if taken[key] {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// keys returns the full keys of the section
+func (s *section) keys() []string {
+	fields := keys(s.typ)
+	full := make([]string, len(fields))
+	for i, field := range fields {
+		full[i] = s.key(field)
+	}
+	return full
+}
+
+// setDefaults registers the `default` tags of the section's fields
+func (s *section) setDefaults(v *viper.Viper) {
+	for i := 0; i < s.typ.NumField(); i++ {
+		field := s.typ.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if value, ok := field.Tag.Lookup("default"); ok && name != "" && name != "-" {
+			v.SetDefault(s.key(name), value)
+		}
+	}
+}
+
+// decode copies the section's keys out of v into a new T and validates it
+func (s *section) decode(v *viper.Viper) (any, []error) {
+	sub := viper.New()
+	for _, field := range keys(s.typ) {
+		if value := v.Get(s.key(field)); value != nil {
+			sub.Set(field, value)
+		}
+	}
+
+	value := reflect.New(s.typ).Interface()
+	if err := sub.Unmarshal(value); err != nil {
+		return nil, []error{fmt.Errorf("failed to unmarshal %s config: %w", s.prefix, err)}
+	}
+	return value, validate(value, s.prefix+"_")
+}
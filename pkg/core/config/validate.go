@@ -0,0 +1,70 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the `validate` tags on cfg and returns one error per failing
+// key, named by its env name (APP_PORT) with prefix rather than the Go field
+func validate(cfg any, prefix string) []error {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return prefix + field.Tag.Get("mapstructure")
+	})
+
+	err := v.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []error{err}
+	}
+
+	typ := reflect.Indirect(reflect.ValueOf(cfg)).Type()
+	problems := make([]error, 0, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		problems = append(problems, errors.New(describe(fe, typ, prefix)))
+	}
+	return problems
+}
+
+// describe turns a validation failure into a message naming the key and value
+func describe(fe validator.FieldError, typ reflect.Type, prefix string) string {
+	key := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return key + " is required"
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s (got %q)", key, strings.ReplaceAll(fe.Param(), " ", ", "), fmt.Sprint(fe.Value()))
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s (got %v)", key, fe.Param(), fe.Value())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s (got %v)", key, fe.Param(), fe.Value())
+	case "ltefield":
+		return fmt.Sprintf("%s must not be greater than %s (got %v)", key, prefix+keyOf(typ, fe.Param()), fe.Value())
+	case "required_if":
+		param := strings.Fields(fe.Param())
+		if len(param) == 2 {
+			return fmt.Sprintf("%s is required when %s is %s", key, prefix+keyOf(typ, param[0]), param[1])
+		}
+	case "required_with":
+		return fmt.Sprintf("%s is required with %s", key, prefix+keyOf(typ, fe.Param()))
+	case "url":
+		return fmt.Sprintf("%s must be a URL (got %q)", key, fmt.Sprint(fe.Value()))
+	}
+	return fmt.Sprintf("%s failed %s validation (got %v)", key, fe.Tag(), fe.Value())
+}
+
+// keyOf returns the env name of the field called name in typ
+func keyOf(typ reflect.Type, name string) string {
+	if field, ok := typ.FieldByName(name); ok {
+		return field.Tag.Get("mapstructure")
+	}
+	return name
+}
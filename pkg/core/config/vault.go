@@ -0,0 +1,181 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrVaultKey is returned when a vault cannot be decrypted with the given passphrase
+var ErrVaultKey = errors.New("wrong vault key or corrupted vault")
+
+// ErrVaultFormat is returned when a vault file is not in a format this version
+// can read, e.g. truncated or edited by hand
+var ErrVaultFormat = errors.New("invalid vault file")
+
+// vaultVersion is the vaultFile.Version Save writes
+const vaultVersion = 1
+
+// vaultFile is the on-disk format: the secrets map as JSON, sealed with
+// AES-256-GCM under a key derived from the passphrase with scrypt
+type vaultFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault is an encrypted local secrets file. It is safe to commit or ship the
+// file; only the passphrase (SECRETS_VAULT_KEY) must stay private.
+type Vault struct {
+	path       string
+	passphrase string
+	secrets    map[string]string
+}
+
+// OpenVault decrypts the vault at path. A missing file is an error (matching
+// os.ErrNotExist), so a mistyped SECRETS_VAULT does not silently yield no secrets.
+func OpenVault(path, passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, errors.New("vault passphrase is empty, set SECRETS_VAULT_KEY")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	return openVault(path, passphrase, data)
+}
+
+// OpenOrCreateVault is like OpenVault but returns an empty vault, which Save
+// will create, when the file does not exist yet
+func OpenOrCreateVault(path, passphrase string) (*Vault, error) {
+	vault, err := OpenVault(path, passphrase)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Vault{path: path, passphrase: passphrase, secrets: map[string]string{}}, nil
+	}
+	return vault, err
+}
+
+func openVault(path, passphrase string, data []byte) (*Vault, error) {
+	vault := &Vault{path: path, passphrase: passphrase, secrets: map[string]string{}}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse vault %s: %w", path, err)
+	}
+	if file.Version != vaultVersion {
+		return nil, fmt.Errorf("%w %s: unsupported version %d", ErrVaultFormat, path, file.Version)
+	}
+	gcm, err := vaultCipher(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != gcm.NonceSize() {
+		// gcm.Open panics on a nonce of the wrong size
+		return nil, fmt.Errorf("%w %s: nonce is %d bytes, want %d", ErrVaultFormat, path, len(file.Nonce), gcm.NonceSize())
+	}
+	plain, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, ErrVaultKey
+	}
+	if err := json.Unmarshal(plain, &vault.secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse vault secrets: %w", err)
+	}
+	return vault, nil
+}
+
+// Secret implements SecretProvider
+func (v *Vault) Secret(ctx context.Context, key string) (string, bool, error) {
+	value, ok := v.secrets[key]
+	return value, ok, nil
+}
+
+// Set stores a secret; call Save to write it
+func (v *Vault) Set(key, value string) {
+	v.secrets[key] = value
+}
+
+// Delete removes a secret; call Save to write it
+func (v *Vault) Delete(key string) {
+	delete(v.secrets, key)
+}
+
+// Keys returns the stored keys, sorted
+func (v *Vault) Keys() []string {
+	keys := make([]string, 0, len(v.secrets))
+	for key := range v.secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Save encrypts the secrets with a fresh salt and nonce and replaces the vault
+// file atomically, so an interrupted write never leaves it truncated
+func (v *Vault) Save() error {
+	plain, err := json.Marshal(v.secrets)
+	if err != nil {
+		return err
+	}
+
+	file := vaultFile{Version: vaultVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	gcm, err := vaultCipher(v.passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Data = gcm.Seal(nil, file.Nonce, plain, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), "."+filepath.Base(v.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), v.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+// vaultCipher derives the AES-256 key for passphrase and salt
+func vaultCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	if _, err := OpenVault(path, "pass"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("OpenVault() on a missing file error = %v, want os.ErrNotExist", err)
+	}
+
+	vault, err := OpenOrCreateVault(path, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault.Set("DB_PASSWORD", "s3cr3t")
+	vault.Set("API_KEY", "k")
+	vault.Delete("API_KEY")
+	vault.Set("JWT_SECRET_KEY", "jwt")
+	if err := vault.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("vault mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want the temp file renamed away", len(entries))
+	}
+
+	reopened, err := OpenVault(path, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DB_PASSWORD", "JWT_SECRET_KEY"}; !reflect.DeepEqual(reopened.Keys(), want) {
+		t.Errorf("Keys() = %v, want %v", reopened.Keys(), want)
+	}
+	if value, ok, err := reopened.Secret(context.Background(), "DB_PASSWORD"); err != nil || !ok || value != "s3cr3t" {
+		t.Errorf("Secret(DB_PASSWORD) = %q, %v, %v", value, ok, err)
+	}
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	vault, err := OpenOrCreateVault(path, "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vault.Set("KEY", "value")
+	if err := vault.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenVault(path, "wrong"); !errors.Is(err, ErrVaultKey) {
+		t.Errorf("OpenVault() error = %v, want ErrVaultKey", err)
+	}
+	if _, err := OpenVault(path, ""); err == nil {
+		t.Error("OpenVault() with an empty passphrase succeeded")
+	}
+}
+
+func TestVaultCorruptFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr error
+	}{
+		{name: "empty object", content: `{}`, wantErr: ErrVaultFormat},
+		{name: "missing nonce", content: `{"version":1,"salt":"AAAAAAAAAAAAAAAAAAAAAA==","data":"AAAA"}`, wantErr: ErrVaultFormat},
+		{name: "short nonce", content: `{"version":1,"salt":"AAAAAAAAAAAAAAAAAAAAAA==","nonce":"AAAA","data":"AAAA"}`, wantErr: ErrVaultFormat},
+		{name: "unknown version", content: `{"version":2,"salt":"AAAAAAAAAAAAAAAAAAAAAA==","nonce":"AAAAAAAAAAAAAAAA","data":"AAAA"}`, wantErr: ErrVaultFormat},
+		{name: "bad ciphertext", content: `{"version":1,"salt":"AAAAAAAAAAAAAAAAAAAAAA==","nonce":"AAAAAAAAAAAAAAAA","data":"AAAA"}`, wantErr: ErrVaultKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.vault")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := OpenVault(path, "pass"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("OpenVault() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "secrets.vault")
+	if err := os.WriteFile(path, []byte(`{"version":1,`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenVault(path, "pass"); err == nil {
+		t.Error("OpenVault() on truncated JSON succeeded")
+	}
+}
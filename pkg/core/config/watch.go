@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/logger"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce collapses the burst of events an editor or a Kubernetes
+// ConfigMap update produces into one reload
+const reloadDebounce = 200 * time.Millisecond
+
+var (
+	current     atomic.Pointer[Config]
+	lastOptions Options
+	watchPaths  []string
+
+	listenersMu sync.Mutex
+	listeners   = map[int]func(old, new *Config){}
+	listenerSeq int
+)
+
+// OnChange registers fn to run after Reload swaps in a configuration that
+// differs from the previous one. It returns a function that unregisters fn.
+func OnChange(fn func(old, new *Config)) func() {
+	listenersMu.Lock()
+	defer listenersMu.Unlock()
+	listenerSeq++
+	id := listenerSeq
+	listeners[id] = fn
+	return func() {
+		listenersMu.Lock()
+		defer listenersMu.Unlock()
+		delete(listeners, id)
+	}
+}
+
+// Reload loads the configuration again from the sources of the last Load. An
+// invalid configuration is reported and the current snapshot is kept.
+func Reload() error {
+	configMutex.Lock()
+	cfg, paths, err := load(lastOptions)
+	if err != nil {
+		configMutex.Unlock()
+		return err
+	}
+	old := current.Swap(cfg)
+	watchPaths = paths
+	configMutex.Unlock()
+
+	if old == nil || reflect.DeepEqual(old, cfg) {
+		return nil
+	}
+	listenersMu.Lock()
+	fns := make([]func(old, new *Config), 0, len(listeners))
+	for _, fn := range listeners {
+		fns = append(fns, fn)
+	}
+	listenersMu.Unlock()
+	for _, fn := range fns {
+		fn(old, cfg)
+	}
+	return nil
+}
+
+// Watch reloads the configuration when one of its files changes or the
+// process receives SIGHUP, until ctx is done. Values that are only read at
+// startup (ports, database pools) still need a restart; subscribe with
+// OnChange to apply the others.
+func Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %w", err)
+	}
+
+	// Watch directories rather than files: editors and ConfigMap updates
+	// replace files, and files that do not exist yet may be created later
+	configMutex.Lock()
+	paths := append([]string(nil), watchPaths...)
+	configMutex.Unlock()
+	watched := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		watched[abs] = true
+		dir := filepath.Dir(abs)
+		if info, err := os.Stat(abs); err == nil && info.IsDir() {
+			dir = abs
+		}
+		if !dirs[dir] {
+			dirs[dir] = true
+			if err := watcher.Add(dir); err != nil {
+				logger.Error("Failed to watch config directory", zap.String("dir", dir), zap.Error(err))
+			}
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(hup)
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Kubernetes swaps mounted files through a "..data" symlink
+				name := filepath.Base(event.Name)
+				if watched[event.Name] || watched[filepath.Dir(event.Name)] || strings.HasPrefix(name, "..") {
+					debounce = time.After(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Config watcher error", zap.Error(err))
+			case <-hup:
+				debounce = time.After(0)
+			case <-debounce:
+				debounce = nil
+				if err := Reload(); err != nil {
+					logger.Error("Config reload failed, keeping the previous configuration", zap.Error(err))
+					continue
+				}
+				logger.Info("Configuration reloaded")
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Dialect identifies the SQL flavour of a database, matching config.Config.DBType
+type Dialect string
+
+const (
+	// Postgres uses $1, $2, ... placeholders
+	Postgres Dialect = "postgres"
+	// MySQL uses ? placeholders
+	MySQL Dialect = "mysql"
+)
+
+// Placeholder returns the bind parameter for position n (1-based)
+func (d Dialect) Placeholder(n int) string {
+	if d == MySQL {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", n)
+}
+
+// Rebind rewrites ? placeholders into the dialect's form. Question marks inside
+// quoted strings or identifiers, -- and /* */ comments and $tag$ bodies are
+// left alone, as are the jsonb operators ?| and ?&. ?? is an escaped literal ?,
+// which is how the jsonb ? operator is written.
+func (d Dialect) Rebind(query string) string {
+	if d == MySQL || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			i = skipTo(&b, query, i, end, i+1, 1)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			i = skipTo(&b, query, i, end, i, 1)
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipTo(&b, query, i, blockCommentEnd(query[i:]), i, 0)
+		case c == '$' && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			i = skipTo(&b, query, i, end, i+len(tag), len(tag))
+		case c == '?' && i+1 < len(query) && query[i+1] == '?':
+			b.WriteByte('?')
+			i += 2
+		case c == '?' && i+1 < len(query) && (query[i+1] == '|' || query[i+1] == '&'):
+			b.WriteString(query[i : i+2])
+			i += 2
+		case c == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+			i++
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipTo copies query[start:] up to and including a closing delimiter of
+// length size found at offset end from base (-1 meaning the rest of query)
+// and returns the index after it
+func skipTo(b *strings.Builder, query string, start, end, base, size int) int {
+	stop := len(query)
+	if end >= 0 {
+		stop = base + end + size
+	}
+	b.WriteString(query[start:stop])
+	return stop
+}
+
+// blockCommentEnd returns the offset just past the end of the (possibly
+// nested) /* comment that query starts with, or -1 if it is unterminated
+func blockCommentEnd(query string) int {
+	depth := 0
+	for i := 0; i+1 < len(query); i++ {
+		switch query[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// dollarTag returns the $tag$ or $$ opening a dollar-quoted string at the
+// start of query, or "" when query starts with a $n parameter or plain $
+func dollarTag(query string) string {
+	for i := 1; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '$':
+			return query[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// Result reports the outcome of Exec. LastInsertID is only filled in by MySQL;
+// use RETURNING with QueryRow on Postgres.
+type Result struct {
+	RowsAffected int64
+	LastInsertID int64
+}
+
+// Row is a single result row
+type Row interface {
+	Scan(dest ...any) error
+}
+
+// Rows is a result set that must be closed
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close()
+}
+
+// Executor runs statements. Queries are written with ? placeholders, which
+// are rewritten for the underlying dialect. On a DB, a ctx from WithTx routes
+// the statement into that transaction.
+type Executor interface {
+	Query(ctx context.Context, query string, args ...any) (Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) Row
+	Exec(ctx context.Context, query string, args ...any) (Result, error)
+}
+
+// Tx is a database transaction
+type Tx interface {
+	Executor
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// DB is the database-agnostic handle used by repositories
+type DB interface {
+	Executor
+	// Begin and BeginTx open a savepoint when ctx is inside WithTx
+	Begin(ctx context.Context) (Tx, error)
+	BeginTx(ctx context.Context, opts TxOptions) (Tx, error)
+	Dialect() Dialect
+	Ping(ctx context.Context) error
+	Close()
+}
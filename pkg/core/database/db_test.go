@@ -0,0 +1,87 @@
+package database
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{
+			name:    "placeholders",
+			dialect: Postgres,
+			query:   "SELECT * FROM users WHERE id = ? AND status = ?",
+			want:    "SELECT * FROM users WHERE id = $1 AND status = $2",
+		},
+		{
+			name:    "mysql unchanged",
+			dialect: MySQL,
+			query:   "SELECT * FROM users WHERE id = ?",
+			want:    "SELECT * FROM users WHERE id = ?",
+		},
+		{
+			name:    "quoted strings and identifiers",
+			dialect: Postgres,
+			query:   `SELECT '?', 'it''s ?', "col?" FROM t WHERE a = ?`,
+			want:    `SELECT '?', 'it''s ?', "col?" FROM t WHERE a = $1`,
+		},
+		{
+			name:    "escaped jsonb key exists",
+			dialect: Postgres,
+			query:   "SELECT * FROM t WHERE data ?? 'key' AND id = ?",
+			want:    "SELECT * FROM t WHERE data ? 'key' AND id = $1",
+		},
+		{
+			name:    "jsonb any and all operators",
+			dialect: Postgres,
+			query:   "SELECT * FROM t WHERE data ?| ? AND data ?& ?",
+			want:    "SELECT * FROM t WHERE data ?| $1 AND data ?& $2",
+		},
+		{
+			name:    "line comment",
+			dialect: Postgres,
+			query:   "SELECT 1 -- why?\nWHERE id = ?",
+			want:    "SELECT 1 -- why?\nWHERE id = $1",
+		},
+		{
+			name:    "unterminated line comment",
+			dialect: Postgres,
+			query:   "SELECT ? -- trailing?",
+			want:    "SELECT $1 -- trailing?",
+		},
+		{
+			name:    "nested block comment",
+			dialect: Postgres,
+			query:   "SELECT /* a? /* b? */ c? */ ?",
+			want:    "SELECT /* a? /* b? */ c? */ $1",
+		},
+		{
+			name:    "dollar quoted body",
+			dialect: Postgres,
+			query:   "SELECT $$ ? $$, $fn$ 'x?' $fn$, ?",
+			want:    "SELECT $$ ? $$, $fn$ 'x?' $fn$, $1",
+		},
+		{
+			name:    "positional parameter is not a dollar tag",
+			dialect: Postgres,
+			query:   "SELECT $1, ?",
+			want:    "SELECT $1, $1",
+		},
+		{
+			name:    "multibyte text",
+			dialect: Postgres,
+			query:   "SELECT 'héllo?' WHERE naïve = ?",
+			want:    "SELECT 'héllo?' WHERE naïve = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Rebind(tt.query); got != tt.want {
+				t.Errorf("Rebind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -17,7 +18,8 @@ type MySQLService struct {
 
 // NewMySQLService creates a new instance of MySQLService with advanced configurations
 func NewMySQLService(cfg *config.Config) (*MySQLService, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
+	// clientFoundRows makes RowsAffected count matched rows, as Postgres does
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&clientFoundRows=true",
 		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 
 	db, err := sql.Open("mysql", dsn)
@@ -28,7 +30,7 @@ func NewMySQLService(cfg *config.Config) (*MySQLService, error) {
 	// Configure connection pooling
 	db.SetMaxOpenConns(cfg.MaxConns)                                          // Maximum number of open connections to the database
 	db.SetMaxIdleConns(cfg.DBMaxIdleConns)                                    // Maximum number of idle connections in the pool
-	db.SetConnMaxLifetime(time.Duration(cfg.DBMaxConnLifetime) * time.Minute) // Maximum amount of time a connection may be reused
+	db.SetConnMaxLifetime(cfg.DBMaxConnLifetime) // Maximum amount of time a connection may be reused
 
 	// Test the database connection
 	if err := testConnection(db); err != nil {
@@ -77,3 +79,134 @@ func (dbService *MySQLService) LogStats() {
 	stats := dbService.db.Stats()
 	log.Printf("MySQL connection pool stats: %+v", stats)
 }
+
+// mysqlDB implements DB over database/sql
+type mysqlDB struct {
+	db *sql.DB
+}
+
+// NewMySQLDB wraps a database/sql handle as a DB
+func NewMySQLDB(db *sql.DB) DB {
+	return &mysqlDB{db: db}
+}
+
+func (m *mysqlDB) Dialect() Dialect {
+	return MySQL
+}
+
+func (m *mysqlDB) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	if state := txFromContext(ctx, m); state != nil {
+		return state.tx.Query(ctx, query, args...)
+	}
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{rows: rows}, nil
+}
+
+func (m *mysqlDB) QueryRow(ctx context.Context, query string, args ...any) Row {
+	if state := txFromContext(ctx, m); state != nil {
+		return state.tx.QueryRow(ctx, query, args...)
+	}
+	return m.db.QueryRowContext(ctx, query, args...)
+}
+
+func (m *mysqlDB) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	if state := txFromContext(ctx, m); state != nil {
+		return state.tx.Exec(ctx, query, args...)
+	}
+	res, err := m.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	return sqlResult(res), nil
+}
+
+func (m *mysqlDB) Begin(ctx context.Context) (Tx, error) {
+	return m.BeginTx(ctx, TxOptions{})
+}
+
+// BeginTx starts a transaction, or a savepoint when ctx is already inside WithTx
+func (m *mysqlDB) BeginTx(ctx context.Context, opts TxOptions) (Tx, error) {
+	if state := txFromContext(ctx, m); state != nil {
+		return state.savepoint(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sqlIsolation(opts.Isolation), ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlTx{tx: tx}, nil
+}
+
+func (m *mysqlDB) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
+func (m *mysqlDB) Close() {
+	m.db.Close()
+}
+
+// mysqlTx implements Tx over a database/sql transaction
+type mysqlTx struct {
+	tx *sql.Tx
+}
+
+func (t *mysqlTx) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{rows: rows}, nil
+}
+
+func (t *mysqlTx) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *mysqlTx) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	res, err := t.tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	return sqlResult(res), nil
+}
+
+func (t *mysqlTx) Commit(ctx context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t *mysqlTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback()
+}
+
+// sqlRows adapts *sql.Rows to Rows
+type sqlRows struct {
+	rows *sql.Rows
+}
+
+func (r *sqlRows) Next() bool             { return r.rows.Next() }
+func (r *sqlRows) Scan(dest ...any) error { return r.rows.Scan(dest...) }
+func (r *sqlRows) Err() error             { return r.rows.Err() }
+func (r *sqlRows) Close()                 { r.rows.Close() }
+
+func sqlIsolation(level IsolationLevel) sql.IsolationLevel {
+	switch level {
+	case LevelReadUncommitted:
+		return sql.LevelReadUncommitted
+	case LevelReadCommitted:
+		return sql.LevelReadCommitted
+	case LevelRepeatableRead:
+		return sql.LevelRepeatableRead
+	case LevelSerializable:
+		return sql.LevelSerializable
+	}
+	return sql.LevelDefault
+}
+
+func sqlResult(res sql.Result) Result {
+	affected, _ := res.RowsAffected()
+	lastID, _ := res.LastInsertId()
+	return Result{RowsAffected: affected, LastInsertID: lastID}
+}
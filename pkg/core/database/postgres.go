@@ -4,9 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,12 +19,37 @@ type PgxDatabaseService struct {
 	pool *pgxpool.Pool
 }
 
+// PostgresDSN builds the primary's connection URL from the DB_* settings,
+// escaping credentials and applying DB_SSLMODE
+func PostgresDSN(cfg *config.Config) string {
+	u := &url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.DBUser, cfg.DBPassword),
+		Host:   net.JoinHostPort(cfg.DBHost, strconv.Itoa(cfg.DBPort)),
+		Path:   "/" + cfg.DBName,
+	}
+	return withSSLMode(u.String(), cfg.DBSSLMode)
+}
+
+// withSSLMode adds sslmode to a URL or keyword/value DSN that does not set
+// one already; an empty mode leaves the driver default
+func withSSLMode(dsn, mode string) string {
+	if mode == "" || strings.Contains(dsn, "sslmode=") {
+		return dsn
+	}
+	if !strings.Contains(dsn, "://") {
+		return dsn + " sslmode=" + mode
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "sslmode=" + url.QueryEscape(mode)
+}
+
 // NewPgxDatabaseService initializes a new database service using pgxpool
 func NewPgxDatabaseService(cfg *config.Config) (*PgxDatabaseService, error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
-
-	config, err := pgxpool.ParseConfig(dsn)
+	config, err := pgxpool.ParseConfig(PostgresDSN(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
@@ -77,3 +107,108 @@ func (db *PgxDatabaseService) HealthCheck() error {
 	}
 	return nil
 }
+
+// postgresDB implements DB over a pgx pool
+type postgresDB struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresDB wraps a pgx pool as a DB
+func NewPostgresDB(pool *pgxpool.Pool) DB {
+	return &postgresDB{pool: pool}
+}
+
+func (db *postgresDB) Dialect() Dialect {
+	return Postgres
+}
+
+func (db *postgresDB) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	if state := txFromContext(ctx, db); state != nil {
+		return state.tx.Query(ctx, query, args...)
+	}
+	rows, err := db.pool.Query(ctx, Postgres.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (db *postgresDB) QueryRow(ctx context.Context, query string, args ...any) Row {
+	if state := txFromContext(ctx, db); state != nil {
+		return state.tx.QueryRow(ctx, query, args...)
+	}
+	return db.pool.QueryRow(ctx, Postgres.Rebind(query), args...)
+}
+
+func (db *postgresDB) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	if state := txFromContext(ctx, db); state != nil {
+		return state.tx.Exec(ctx, query, args...)
+	}
+	tag, err := db.pool.Exec(ctx, Postgres.Rebind(query), args...)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{RowsAffected: tag.RowsAffected()}, nil
+}
+
+func (db *postgresDB) Begin(ctx context.Context) (Tx, error) {
+	return db.BeginTx(ctx, TxOptions{})
+}
+
+// BeginTx starts a transaction, or a savepoint when ctx is already inside WithTx
+func (db *postgresDB) BeginTx(ctx context.Context, opts TxOptions) (Tx, error) {
+	if state := txFromContext(ctx, db); state != nil {
+		return state.savepoint(ctx)
+	}
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(opts.Isolation)}
+	if opts.ReadOnly {
+		txOpts.AccessMode = pgx.ReadOnly
+	}
+	tx, err := db.pool.BeginTx(ctx, txOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{tx: tx}, nil
+}
+
+func (db *postgresDB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
+func (db *postgresDB) Close() {
+	db.pool.Close()
+}
+
+// postgresTx implements Tx over a pgx transaction
+type postgresTx struct {
+	tx pgx.Tx
+}
+
+func (t *postgresTx) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := t.tx.Query(ctx, Postgres.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (t *postgresTx) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return t.tx.QueryRow(ctx, Postgres.Rebind(query), args...)
+}
+
+func (t *postgresTx) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	tag, err := t.tx.Exec(ctx, Postgres.Rebind(query), args...)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{RowsAffected: tag.RowsAffected()}, nil
+}
+
+func (t *postgresTx) Commit(ctx context.Context) error {
+	return t.tx.Commit(ctx)
+}
+
+func (t *postgresTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback(ctx)
+}
@@ -0,0 +1,278 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsolationLevel is a transaction isolation level; the zero value uses the server default
+type IsolationLevel string
+
+const (
+	LevelDefault         IsolationLevel = ""
+	LevelReadUncommitted IsolationLevel = "read uncommitted"
+	LevelReadCommitted   IsolationLevel = "read committed"
+	LevelRepeatableRead  IsolationLevel = "repeatable read"
+	LevelSerializable    IsolationLevel = "serializable"
+)
+
+// DefaultMaxRetries is how many times WithTx retries after a serialization failure
+const DefaultMaxRetries = 3
+
+// TxOptions configures BeginTx
+type TxOptions struct {
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
+// TxOption customises WithTx
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	opts       TxOptions
+	maxRetries int
+}
+
+// WithIsolation sets the isolation level of the transaction
+func WithIsolation(level IsolationLevel) TxOption {
+	return func(c *txConfig) {
+		c.opts.Isolation = level
+	}
+}
+
+// WithReadOnly starts a read-only transaction
+func WithReadOnly() TxOption {
+	return func(c *txConfig) {
+		c.opts.ReadOnly = true
+	}
+}
+
+// WithMaxRetries overrides DefaultMaxRetries; 0 disables retrying
+func WithMaxRetries(n int) TxOption {
+	return func(c *txConfig) {
+		c.maxRetries = n
+	}
+}
+
+type txKey struct{}
+
+// txState is the transaction WithTx stored in the context
+type txState struct {
+	owner DB
+	tx    Tx
+	seq   *atomic.Int64 // shared by nested savepoints so their names are unique
+
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context)
+}
+
+// queue adds fn to run after this transaction or savepoint commits
+func (s *txState) queue(fn func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.afterCommit = append(s.afterCommit, fn)
+}
+
+// hooks returns and clears the queued after-commit hooks
+func (s *txState) hooks() []func(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := s.afterCommit
+	s.afterCommit = nil
+	return hooks
+}
+
+// AfterCommit runs fn once the outermost transaction ctx is running in has
+// committed, or right away when ctx carries no transaction. Hooks queued in a
+// savepoint that rolls back, or in an attempt that is retried, are dropped.
+// Use it for side effects such as cache invalidation that must not be seen
+// before the data is. fn receives a context that is not cancelled with ctx.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn(context.WithoutCancel(ctx))
+		return
+	}
+	state.queue(fn)
+}
+
+// txFromContext returns the transaction on db that ctx is running in, if any
+func txFromContext(ctx context.Context, db DB) *txState {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok || state.owner != db {
+		return nil
+	}
+	return state
+}
+
+// InTx reports whether ctx carries a transaction started by WithTx
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// WithTx runs fn in a transaction on db. The transaction travels in the context
+// passed to fn, so any Query, QueryRow or Exec on db with that context joins it,
+// and Begin opens a savepoint instead of a new transaction.
+//
+// fn returning an error (or panicking) rolls the transaction back. A nested
+// WithTx runs in a savepoint and only rolls back its own work. The outermost
+// call retries fn on serialization failures and deadlocks, so fn must be safe
+// to run more than once.
+func WithTx(ctx context.Context, db DB, fn func(ctx context.Context) error, opts ...TxOption) error {
+	cfg := txConfig{maxRetries: DefaultMaxRetries}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if state := txFromContext(ctx, db); state != nil {
+		return runSavepoint(ctx, state, fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, cfg.opts, fn)
+		if err == nil || attempt >= cfg.maxRetries || !IsSerializationFailure(err) {
+			return err
+		}
+
+		backoff := time.Duration(attempt+1)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func runTx(ctx context.Context, db DB, opts TxOptions, fn func(ctx context.Context) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Roll back even when ctx has been cancelled
+	rollbackCtx := context.WithoutCancel(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback(rollbackCtx)
+			panic(p)
+		}
+	}()
+
+	state := &txState{owner: db, tx: tx, seq: new(atomic.Int64)}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		tx.Rollback(rollbackCtx)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for _, hook := range state.hooks() {
+		hook(rollbackCtx)
+	}
+	return nil
+}
+
+func runSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	sp, err := state.savepoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	rollbackCtx := context.WithoutCancel(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			sp.Rollback(rollbackCtx)
+			panic(p)
+		}
+	}()
+
+	nested := &txState{owner: state.owner, tx: sp, seq: state.seq}
+	if err := fn(context.WithValue(ctx, txKey{}, nested)); err != nil {
+		sp.Rollback(rollbackCtx)
+		return err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return err
+	}
+	// The savepoint's work now belongs to the enclosing transaction, and so do its hooks
+	for _, hook := range nested.hooks() {
+		state.queue(hook)
+	}
+	return nil
+}
+
+// savepoint opens a savepoint inside the transaction
+func (s *txState) savepoint(ctx context.Context) (Tx, error) {
+	name := fmt.Sprintf("rootx_sp_%d", s.seq.Add(1))
+	if _, err := s.tx.Exec(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	return &savepointTx{parent: s.tx, name: name}, nil
+}
+
+// savepointTx is a Tx backed by a savepoint: Commit releases it and Rollback
+// undoes the work done since it was created
+type savepointTx struct {
+	parent Tx
+	name   string
+	done   bool
+}
+
+func (t *savepointTx) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	return t.parent.Query(ctx, query, args...)
+}
+
+func (t *savepointTx) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return t.parent.QueryRow(ctx, query, args...)
+}
+
+func (t *savepointTx) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	return t.parent.Exec(ctx, query, args...)
+}
+
+func (t *savepointTx) Commit(ctx context.Context) error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	if _, err := t.parent.Exec(ctx, "RELEASE SAVEPOINT "+t.name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+func (t *savepointTx) Rollback(ctx context.Context) error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	if _, err := t.parent.Exec(ctx, "ROLLBACK TO SAVEPOINT "+t.name); err != nil {
+		return fmt.Errorf("failed to roll back savepoint: %w", err)
+	}
+	return nil
+}
+
+// IsSerializationFailure reports whether err means the transaction lost a
+// conflict with a concurrent one and can be retried from the start
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		// ER_LOCK_DEADLOCK
+		return myErr.Number == 1213
+	}
+	return false
+}
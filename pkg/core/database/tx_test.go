@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeDB records the statements WithTx sends it
+type fakeDB struct {
+	log []string
+}
+
+func (db *fakeDB) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return nil
+}
+
+func (db *fakeDB) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	db.log = append(db.log, query)
+	return Result{}, nil
+}
+
+func (db *fakeDB) Begin(ctx context.Context) (Tx, error) {
+	return db.BeginTx(ctx, TxOptions{})
+}
+
+func (db *fakeDB) BeginTx(ctx context.Context, opts TxOptions) (Tx, error) {
+	db.log = append(db.log, "BEGIN")
+	return &fakeTx{db: db}, nil
+}
+
+func (db *fakeDB) Dialect() Dialect               { return Postgres }
+func (db *fakeDB) Ping(ctx context.Context) error { return nil }
+func (db *fakeDB) Close()                         {}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx *fakeTx) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	return tx.db.Query(ctx, query, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return tx.db.QueryRow(ctx, query, args...)
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, query string, args ...any) (Result, error) {
+	return tx.db.Exec(ctx, query, args...)
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.db.log = append(tx.db.log, "COMMIT")
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.db.log = append(tx.db.log, "ROLLBACK")
+	return nil
+}
+
+func TestWithTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	serialization := &pgconn.PgError{Code: "40001"}
+	uniqueViolation := &pgconn.PgError{Code: "23505"}
+
+	tests := []struct {
+		name     string
+		opts     []TxOption
+		fn       func(ctx context.Context, db DB, attempt int) error
+		wantErr  error
+		wantLog  []string
+		wantRuns int
+	}{
+		{
+			name: "commit",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				db.Exec(ctx, "INSERT")
+				AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "HOOK") })
+				return nil
+			},
+			wantLog:  []string{"BEGIN", "INSERT", "COMMIT", "HOOK"},
+			wantRuns: 1,
+		},
+		{
+			name: "error rolls back and drops hooks",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "HOOK") })
+				return errFailed
+			},
+			wantErr:  errFailed,
+			wantLog:  []string{"BEGIN", "ROLLBACK"},
+			wantRuns: 1,
+		},
+		{
+			name: "nested savepoint commits with the outer transaction",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				return WithTx(ctx, db, func(ctx context.Context) error {
+					AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "HOOK") })
+					return nil
+				})
+			},
+			wantLog:  []string{"BEGIN", "SAVEPOINT rootx_sp_1", "RELEASE SAVEPOINT rootx_sp_1", "COMMIT", "HOOK"},
+			wantRuns: 1,
+		},
+		{
+			name: "nested savepoint rolls back only its own work",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "OUTER HOOK") })
+				err := WithTx(ctx, db, func(ctx context.Context) error {
+					AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "INNER HOOK") })
+					return errFailed
+				})
+				if !errors.Is(err, errFailed) {
+					return errors.New("nested error not returned")
+				}
+				return WithTx(ctx, db, func(ctx context.Context) error { return nil })
+			},
+			wantLog: []string{
+				"BEGIN",
+				"SAVEPOINT rootx_sp_1", "ROLLBACK TO SAVEPOINT rootx_sp_1",
+				"SAVEPOINT rootx_sp_2", "RELEASE SAVEPOINT rootx_sp_2",
+				"COMMIT", "OUTER HOOK",
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "serialization failure is retried",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				AfterCommit(ctx, func(context.Context) { db.Exec(ctx, "HOOK") })
+				if attempt == 1 {
+					return serialization
+				}
+				return nil
+			},
+			wantLog:  []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT", "HOOK"},
+			wantRuns: 2,
+		},
+		{
+			name: "retries give up after the limit",
+			opts: []TxOption{WithMaxRetries(1)},
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				return serialization
+			},
+			wantErr:  serialization,
+			wantLog:  []string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"},
+			wantRuns: 2,
+		},
+		{
+			name: "other errors are not retried",
+			fn: func(ctx context.Context, db DB, attempt int) error {
+				return uniqueViolation
+			},
+			wantErr:  uniqueViolation,
+			wantLog:  []string{"BEGIN", "ROLLBACK"},
+			wantRuns: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{}
+			runs := 0
+			err := WithTx(context.Background(), db, func(ctx context.Context) error {
+				if !InTx(ctx) {
+					t.Error("InTx() = false inside WithTx")
+				}
+				runs++
+				return tt.fn(ctx, db, runs)
+			}, tt.opts...)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("fn ran %d times, want %d", runs, tt.wantRuns)
+			}
+			if !reflect.DeepEqual(db.log, tt.wantLog) {
+				t.Errorf("statements = %q, want %q", db.log, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestAfterCommitOutsideTx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	AfterCommit(ctx, func(ctx context.Context) {
+		ran = ctx.Err() == nil
+	})
+	if !ran {
+		t.Error("AfterCommit() outside a transaction did not run fn with a live context")
+	}
+}
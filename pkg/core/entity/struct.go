@@ -1,12 +1,15 @@
 package entity
 
 type Pagination struct {
-	TotalItems        int  `json:"total_items"`
-	TotalCurrentItems int  `json:"total_current_items"`
-	TotalPages        int  `json:"total_pages"`
-	CurrentPage       int  `json:"current_page"`
-	NextPage          *int `json:"next_page,omitempty"`
-	PreviousPage      *int `json:"prev_page,omitempty"`
-	FirstPage         int  `json:"first_page"`
-	LastPage          int  `json:"last_page"`
+	TotalItems        int    `json:"total_items"`
+	TotalEstimated    bool   `json:"total_estimated,omitempty"`
+	TotalCurrentItems int    `json:"total_current_items"`
+	TotalPages        int    `json:"total_pages"`
+	CurrentPage       int    `json:"current_page"`
+	NextPage          *int   `json:"next_page,omitempty"`
+	PreviousPage      *int   `json:"prev_page,omitempty"`
+	FirstPage         int    `json:"first_page"`
+	LastPage          int    `json:"last_page"`
+	NextCursor        string `json:"next_cursor,omitempty"`
+	PrevCursor        string `json:"prev_cursor,omitempty"`
 }
@@ -49,3 +49,52 @@ func DBHealthHandler(app *app.App) http.HandlerFunc {
 		w.Write([]byte("Database is healthy"))
 	}
 }
+
+// ComponentsHealthResponse represents the health of every registered component
+type ComponentsHealthResponse struct {
+	Status     string                `json:"status"`
+	Components []app.ComponentHealth `json:"components"`
+}
+
+// ComponentsHealthHandler reports the health hooks of all started components,
+// answering 503 when any of them fails
+func ComponentsHealthHandler(application *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := application.Health(r.Context())
+
+		response := ComponentsHealthResponse{Status: "OK", Components: report}
+		status := http.StatusOK
+		for _, component := range report {
+			if !component.Healthy {
+				response.Status = "DEGRADED"
+				status = http.StatusServiceUnavailable
+				break
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			application.Logger.Error("Error writing response", zap.Error(err))
+		}
+	}
+}
+
+// ReadinessHandler answers 200 while the app accepts traffic and 503 once
+// shutdown has started, so orchestrators stop routing before connections close
+func ReadinessHandler(application *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := HealthCheckResponse{Status: "READY"}
+		status := http.StatusOK
+		if !application.Ready() {
+			response.Status = "SHUTTING_DOWN"
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			application.Logger.Error("Error writing response", zap.Error(err))
+		}
+	}
+}
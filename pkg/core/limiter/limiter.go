@@ -92,6 +92,18 @@ func (i *IPRateLimiter) cleanup() {
 	}
 }
 
+// SetLimit changes the rate and burst for new and existing IPs
+func (i *IPRateLimiter) SetLimit(r rate.Limit, b int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.r, i.b = r, b
+	for _, entry := range i.ips {
+		entry.limiter.SetLimit(r)
+		entry.limiter.SetBurst(b)
+	}
+}
+
 // GetIPCount returns the current number of IP limiters
 func (i *IPRateLimiter) GetIPCount() int {
 	i.mu.RLock()
@@ -1,23 +1,37 @@
 package logger
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 )
 
 var (
 	Logger *zap.Logger
+	level  = zap.NewAtomicLevelAt(zap.InfoLevel)
 )
 
 // Init initializes the logger
 func Init() error {
+	cfg := zap.NewProductionConfig()
+	cfg.Level = level
 	var err error
-	Logger, err = zap.NewProduction()
+	Logger, err = cfg.Build()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// SetLevel changes the minimum level (debug, info, warn, error) of Logger,
+// also after Init
+func SetLevel(text string) error {
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", text, err)
+	}
+	return nil
+}
+
 // Sync flushes any buffered log entries
 func Sync() {
 	if Logger != nil {
@@ -2,14 +2,41 @@ package middleware
 
 import (
 	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/core/config"
 )
 
 // CORSMiddleware is a middleware function that adds CORS headers to HTTP responses.
+// Origins, methods and headers come from CORS_ALLOWED_* and are read per
+// request, so a config reload applies without a restart.
 func CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Change "*" to specific domains if needed
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		origins := []string{"*"}
+		methods := "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+		headers := "Content-Type, Authorization, If-Match"
+		if cfg := config.GetConfig(); cfg != nil {
+			if len(cfg.CorsOrigins) > 0 {
+				origins = cfg.CorsOrigins
+			}
+			if len(cfg.CorsMethods) > 0 {
+				methods = strings.Join(cfg.CorsMethods, ", ")
+			}
+			if len(cfg.CorsHeaders) > 0 {
+				headers = strings.Join(cfg.CorsHeaders, ", ")
+			}
+		}
+
+		if slices.Contains(origins, "*") {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin := r.Header.Get("Origin"); origin != "" && slices.Contains(origins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", methods)
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		// Handle preflight requests
 		if r.Method == http.MethodOptions {
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/config"
@@ -69,21 +71,67 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
-// LimiterMiddleware creates a new rate limiting middleware
-func LimiterMiddleware(next http.Handler) http.Handler {
-	// Initialize configuration
-	config := loadRateLimitConfig()
-	if !config.Enabled {
+// The limiter state is shared by every LimiterMiddleware call, so wrapping
+// many routes still means one config subscription and one cleanup goroutine
+var (
+	rateLimitOnce   sync.Once
+	rateLimitConfig atomic.Pointer[RateLimitConfig]
+	rateLimitMu     sync.Mutex
+	rateLimiter     atomic.Pointer[limiter.IPRateLimiter]
+)
+
+// initRateLimit loads the rate limit config and subscribes to reloads once
+func initRateLimit() {
+	initial := loadRateLimitConfig(config.GetConfig())
+	rateLimitConfig.Store(&initial)
+	if !initial.Enabled {
 		log.Println("Rate limiting is disabled.")
-		return next
 	}
 
 	// Initialize IP lists
-	initializeIPLists(config)
+	initializeIPLists(initial)
+
+	config.OnChange(func(old, new *config.Config) {
+		updated := loadRateLimitConfig(new)
+		rateLimitMu.Lock()
+		defer rateLimitMu.Unlock()
+		rateLimitConfig.Store(&updated)
+		if rl := rateLimiter.Load(); rl != nil {
+			rl.SetLimit(rate.Every(updated.Duration), updated.Limit)
+		}
+	})
+}
 
-	var rateLimiter = limiter.NewIPRateLimiter(rate.Every(config.Duration), config.Limit)
+// sharedRateLimiter returns the IP limiter, creating it the first time
+// rate limiting is enabled
+func sharedRateLimiter() *limiter.IPRateLimiter {
+	if rl := rateLimiter.Load(); rl != nil {
+		return rl
+	}
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	if rl := rateLimiter.Load(); rl != nil {
+		return rl
+	}
+	cfg := rateLimitConfig.Load()
+	rl := limiter.NewIPRateLimiter(rate.Every(cfg.Duration), cfg.Limit)
+	rateLimiter.Store(rl)
+	return rl
+}
+
+// LimiterMiddleware creates a new rate limiting middleware. RATE_LIMIT_ENABLED,
+// RATE_LIMIT and RATE_LIMIT_DURATION follow config reloads.
+func LimiterMiddleware(next http.Handler) http.Handler {
+	rateLimitOnce.Do(initRateLimit)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := rateLimitConfig.Load()
+		if !config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rateLimiter := sharedRateLimiter()
+
 		clientIP := getClientIP(r)
 
 		// Check whitelist
@@ -102,11 +150,11 @@ func LimiterMiddleware(next http.Handler) http.Handler {
 		limiter := rateLimiter.GetLimiter(clientIP)
 		
 		// Set standard rate limit headers
-		setRateLimitHeaders(w, limiter, config)
+		setRateLimitHeaders(w, limiter, *config)
 
 		// Check if rate limit is exceeded
 		if !limiter.Allow() {
-			handleRateLimitExceeded(w, r, clientIP, config)
+			handleRateLimitExceeded(w, r, clientIP, *config)
 			return
 		}
 
@@ -118,19 +166,19 @@ func LimiterMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func loadRateLimitConfig() RateLimitConfig {
-	duration, err := time.ParseDuration(os.Getenv("RATE_LIMIT_DURATION"))
-	if err != nil {
+func loadRateLimitConfig(cfg *config.Config) RateLimitConfig {
+	duration := cfg.RateLimitDuration
+	if duration <= 0 {
 		duration = time.Minute
 	}
 
-	limit := config.GlobalConfig.RateLimit
+	limit := cfg.RateLimit
 	if limit <= 0 {
 		limit = 100
 	}
 
 	return RateLimitConfig{
-		Enabled:      config.GlobalConfig.RateLimitEnabled,
+		Enabled:      cfg.RateLimitEnabled,
 		Limit:        limit,
 		Duration:     duration,
 		WhitelistIPs: strings.Split(os.Getenv("RATE_LIMIT_WHITELIST"), ","),
@@ -0,0 +1,217 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Postgres dialect name, matching config.Config.DBType
+	Postgres = "postgres"
+	// MySQL dialect name, matching config.Config.DBType
+	MySQL = "mysql"
+)
+
+// Conn is the minimal database surface used by the migration and seeder runners
+type Conn interface {
+	Dialect() string
+	Exec(ctx context.Context, query string, args ...any) error
+	QueryStrings(ctx context.Context, query string, args ...any) ([]string, error)
+	// Lock blocks until the named database-wide lock is held and returns its release func
+	Lock(ctx context.Context, name string) (func(), error)
+	// Tx runs fn in a transaction, committing when it returns nil
+	Tx(ctx context.Context, fn func(tx Conn) error) error
+}
+
+// errLockInTx is returned by Lock on a Conn passed to a Tx callback
+var errLockInTx = errors.New("cannot take a migration lock inside a transaction")
+
+// lockPollInterval is how long MySQL GET_LOCK waits per attempt before ctx is re-checked
+const lockPollInterval = 5
+
+// pgxQuerier is implemented by pgx pools and transactions
+type pgxQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// pgxConn adapts a pgx pool, or a transaction when pool is nil, to Conn
+type pgxConn struct {
+	pool *pgxpool.Pool
+	q    pgxQuerier
+}
+
+// NewPgxConn wraps a Postgres connection pool
+func NewPgxConn(pool *pgxpool.Pool) Conn {
+	return &pgxConn{pool: pool, q: pool}
+}
+
+func (c *pgxConn) Dialect() string {
+	return Postgres
+}
+
+func (c *pgxConn) Exec(ctx context.Context, query string, args ...any) error {
+	_, err := c.q.Exec(ctx, query, args...)
+	return err
+}
+
+func (c *pgxConn) QueryStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := c.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Lock takes a session-level advisory lock on a dedicated pool connection
+func (c *pgxConn) Lock(ctx context.Context, name string) (func(), error) {
+	if c.pool == nil {
+		return nil, errLockInTx
+	}
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for lock: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", name); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to take advisory lock %s: %w", name, err)
+	}
+	return func() {
+		conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", name)
+		conn.Release()
+	}, nil
+}
+
+func (c *pgxConn) Tx(ctx context.Context, fn func(tx Conn) error) error {
+	if c.pool == nil {
+		return fn(c)
+	}
+	return pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+		return fn(&pgxConn{q: tx})
+	})
+}
+
+// sqlQuerier is implemented by *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// sqlConn adapts a database/sql handle, or a transaction when db is nil, to Conn
+type sqlConn struct {
+	db      *sql.DB
+	q       sqlQuerier
+	dialect string
+}
+
+// NewSQLConn wraps a database/sql handle opened for the given dialect
+func NewSQLConn(db *sql.DB, dialect string) Conn {
+	return &sqlConn{db: db, q: db, dialect: dialect}
+}
+
+func (c *sqlConn) Dialect() string {
+	return c.dialect
+}
+
+func (c *sqlConn) Exec(ctx context.Context, query string, args ...any) error {
+	_, err := c.q.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c *sqlConn) QueryStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := c.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// Lock takes a MySQL named lock (GET_LOCK) on a dedicated connection, polling until it is granted
+func (c *sqlConn) Lock(ctx context.Context, name string) (func(), error) {
+	if c.db == nil {
+		return nil, errLockInTx
+	}
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for lock: %w", err)
+	}
+
+	for {
+		var granted sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, lockPollInterval).Scan(&granted); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to take lock %s: %w", name, err)
+		}
+		if granted.Valid && granted.Int64 == 1 {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("gave up waiting for lock %s: %w", name, err)
+		}
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+		conn.Close()
+	}, nil
+}
+
+func (c *sqlConn) Tx(ctx context.Context, fn func(tx Conn) error) error {
+	if c.db == nil {
+		return fn(c)
+	}
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&sqlConn{q: tx, dialect: c.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// placeholder returns the bind parameter for position n (1-based) in the given dialect
+func placeholder(dialect string, n int) string {
+	if dialect == MySQL {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", n)
+}
+
+// placeholders returns a comma separated list of count bind parameters
+func placeholders(dialect string, count int) string {
+	parts := make([]string, count)
+	for i := range parts {
+		parts[i] = placeholder(dialect, i+1)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DropPrefix starts the commented-out statements Diff emits when running them
+// could lose data or fail: DROP COLUMN without drops enabled, and NOT NULL
+// changes on columns with no default to backfill NULLs with
+const DropPrefix = "-- rootx: "
+
+// Diff returns the statements that bring current in line with desired. Only
+// tables present in desired are considered, so tables owned by other tools are
+// never dropped. Columns missing from desired are dropped only with drop set;
+// otherwise the DROP COLUMN is emitted commented out, since an untagged field
+// or a legacy column would lose its data.
+func Diff(desired, current *Schema, drop bool) []string {
+	dialect := desired.Dialect
+	var statements []string
+	for _, name := range desired.TableNames() {
+		want := desired.Tables[name]
+		have, ok := current.Tables[name]
+		if !ok {
+			statements = append(statements, CreateTableSQL(dialect, want))
+			continue
+		}
+
+		for _, column := range want.Columns {
+			existing := have.Column(column.Name)
+			if existing == nil {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", name, columnDefinition(dialect, column, true)))
+				continue
+			}
+			if column.PrimaryKey {
+				continue
+			}
+			statements = append(statements, alterColumn(dialect, name, column, *existing)...)
+		}
+
+		for _, column := range have.Columns {
+			if want.Column(column.Name) != nil {
+				continue
+			}
+			statement := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", name, column.Name)
+			if !drop {
+				statement = fmt.Sprintf("%scolumn %s.%s has no db tag; uncomment to drop it and its data\n-- %s",
+					DropPrefix, name, column.Name, statement)
+			}
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// CreateTableSQL renders a CREATE TABLE statement for t
+func CreateTableSQL(dialect string, t *Table) string {
+	lines := make([]string, 0, len(t.Columns))
+	for _, column := range t.Columns {
+		lines = append(lines, "    "+columnDefinition(dialect, column, false))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", t.Name, strings.Join(lines, ",\n"))
+}
+
+// alterColumn emits type and nullability changes for an existing column. A
+// column becoming NOT NULL has its NULLs backfilled with its default first;
+// without a default the change is emitted commented out.
+func alterColumn(dialect, table string, want, have Column) []string {
+	typeChanged := typeFamily(dialect, want.Type) != typeFamily(dialect, have.Type)
+	nullChanged := want.Nullable != have.Nullable
+	if !typeChanged && !nullChanged {
+		return nil
+	}
+
+	var statements []string
+	notNull := nullChanged && !want.Nullable
+	if notNull && want.Default != "" {
+		statements = append(statements, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL;",
+			table, want.Name, want.Default, want.Name))
+	}
+	blocked := func(statement string) string {
+		return fmt.Sprintf("%scolumn %s.%s has no default to replace its NULLs; backfill it, then uncomment\n-- %s",
+			DropPrefix, table, want.Name, statement)
+	}
+
+	if dialect == MySQL {
+		statement := fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, columnDefinition(dialect, want, false))
+		if !notNull || want.Default != "" {
+			return append(statements, statement)
+		}
+		if typeChanged {
+			// Change the type now and leave the column nullable
+			nullable := want
+			nullable.Nullable = true
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, columnDefinition(dialect, nullable, false)))
+		}
+		return append(statements, blocked(statement))
+	}
+
+	if typeChanged {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;",
+			table, want.Name, want.Type, want.Name, want.Type))
+	}
+	if nullChanged {
+		action := "SET NOT NULL"
+		if want.Nullable {
+			action = "DROP NOT NULL"
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, want.Name, action)
+		if notNull && want.Default == "" {
+			statement = blocked(statement)
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// columnDefinition renders "name TYPE [NOT NULL] [DEFAULT x]". Defaults are only
+// emitted for timestamps, or for every NOT NULL column when adding to an
+// existing table so the statement succeeds on tables with rows.
+func columnDefinition(dialect string, c Column, withDefault bool) string {
+	if c.PrimaryKey {
+		if dialect == MySQL {
+			return fmt.Sprintf("%s %s AUTO_INCREMENT PRIMARY KEY", c.Name, c.Type)
+		}
+		switch strings.ToUpper(c.Type) {
+		case "BIGINT":
+			return c.Name + " BIGSERIAL PRIMARY KEY"
+		case "INTEGER", "SMALLINT":
+			return c.Name + " SERIAL PRIMARY KEY"
+		}
+		return fmt.Sprintf("%s %s PRIMARY KEY", c.Name, c.Type)
+	}
+
+	def := c.Name + " " + c.Type
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != "" && (withDefault || c.Default == "CURRENT_TIMESTAMP") {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
@@ -0,0 +1,94 @@
+package migration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAlterColumnNotNull(t *testing.T) {
+	nullable := Column{Name: "age", Type: "INTEGER", Nullable: true}
+	withDefault := Column{Name: "age", Type: "INTEGER", Default: "0"}
+	noDefault := Column{Name: "age", Type: "INTEGER"}
+	tests := []struct {
+		name    string
+		dialect string
+		want    Column
+		have    Column
+		result  []string
+	}{
+		{
+			name:    "postgres backfills the default",
+			dialect: Postgres,
+			want:    withDefault,
+			have:    nullable,
+			result: []string{
+				"UPDATE users SET age = 0 WHERE age IS NULL;",
+				"ALTER TABLE users ALTER COLUMN age SET NOT NULL;",
+			},
+		},
+		{
+			name:    "postgres without default is commented out",
+			dialect: Postgres,
+			want:    noDefault,
+			have:    nullable,
+			result: []string{
+				DropPrefix + "column users.age has no default to replace its NULLs; backfill it, then uncomment\n" +
+					"-- ALTER TABLE users ALTER COLUMN age SET NOT NULL;",
+			},
+		},
+		{
+			name:    "postgres drop not null",
+			dialect: Postgres,
+			want:    nullable,
+			have:    noDefault,
+			result:  []string{"ALTER TABLE users ALTER COLUMN age DROP NOT NULL;"},
+		},
+		{
+			name:    "mysql backfills the default",
+			dialect: MySQL,
+			want:    withDefault,
+			have:    nullable,
+			result: []string{
+				"UPDATE users SET age = 0 WHERE age IS NULL;",
+				"ALTER TABLE users MODIFY COLUMN age INTEGER NOT NULL;",
+			},
+		},
+		{
+			name:    "mysql type change kept, not null commented out",
+			dialect: MySQL,
+			want:    Column{Name: "age", Type: "VARCHAR(255)"},
+			have:    nullable,
+			result: []string{
+				"ALTER TABLE users MODIFY COLUMN age VARCHAR(255);",
+				DropPrefix + "column users.age has no default to replace its NULLs; backfill it, then uncomment\n" +
+					"-- ALTER TABLE users MODIFY COLUMN age VARCHAR(255) NOT NULL;",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := alterColumn(tt.dialect, "users", tt.want, tt.have)
+			if !reflect.DeepEqual(got, tt.result) {
+				t.Errorf("alterColumn() = %q, want %q", got, tt.result)
+			}
+		})
+	}
+}
+
+func TestDiffOutputPassesLint(t *testing.T) {
+	desired := &Schema{Dialect: Postgres, Tables: map[string]*Table{
+		"users": {Name: "users", Columns: []Column{{Name: "id", Type: "BIGINT", PrimaryKey: true}, {Name: "age", Type: "INTEGER", Default: "0"}}},
+	}}
+	current := &Schema{Dialect: Postgres, Tables: map[string]*Table{
+		"users": {Name: "users", Columns: []Column{{Name: "id", Type: "BIGINT", PrimaryKey: true}, {Name: "age", Type: "INTEGER", Nullable: true}}},
+	}}
+	findings, err := LintSQL("diff.sql", strings.Join(Diff(desired, current, false), "\n"), Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HasErrors(findings) {
+		t.Errorf("generated migration fails lint: %v", findings)
+	}
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isTrackingTable reports whether table belongs to the rootx runners
+func isTrackingTable(table string) bool {
+	return table == MigrationsTable || table == SeedersTable
+}
+
+// DumpSQL renders schema as a canonical SQL script: tables in name order with
+// columns in declaration order, followed by secondary indexes in name order.
+// The rootx tracking tables are left out, as is everything listed by
+// UnsupportedObjects.
+func DumpSQL(schema *Schema) string {
+	var b strings.Builder
+	for _, name := range schema.TableNames() {
+		if isTrackingTable(name) {
+			continue
+		}
+		b.WriteString(dumpTable(schema.Dialect, schema.Tables[name]))
+		b.WriteString("\n\n")
+	}
+
+	for _, index := range schema.Indexes {
+		if isTrackingTable(index.Table) {
+			continue
+		}
+		b.WriteString(strings.TrimSuffix(index.SQL, ";"))
+		b.WriteString(";\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func dumpTable(dialect string, t *Table) string {
+	inlinePK := len(t.PrimaryKey) == 1
+	lines := make([]string, 0, len(t.Columns)+1)
+	for _, column := range t.Columns {
+		lines = append(lines, "    "+dumpColumn(dialect, column, inlinePK))
+	}
+	if len(t.PrimaryKey) > 1 {
+		lines = append(lines, fmt.Sprintf("    PRIMARY KEY (%s)", strings.Join(t.PrimaryKey, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n%s\n);", t.Name, strings.Join(lines, ",\n"))
+}
+
+// dumpColumn renders a live column. Auto-increment keys are written as
+// SERIAL/BIGSERIAL (Postgres) or AUTO_INCREMENT (MySQL) so the dump replays cleanly.
+func dumpColumn(dialect string, c Column, inlinePK bool) string {
+	sqlType := strings.ToUpper(c.Type)
+	if c.AutoIncrement && dialect != MySQL {
+		if strings.HasPrefix(sqlType, "BIGINT") {
+			sqlType = "BIGSERIAL"
+		} else {
+			sqlType = "SERIAL"
+		}
+	}
+
+	def := c.Name + " " + sqlType
+	if c.AutoIncrement && dialect == MySQL {
+		def += " AUTO_INCREMENT"
+	}
+	if c.PrimaryKey && inlinePK {
+		return def + " PRIMARY KEY"
+	}
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != "" {
+		def += " DEFAULT " + dumpDefault(dialect, c)
+	}
+	return def
+}
+
+// dumpDefault quotes MySQL string defaults, which information_schema reports unquoted
+func dumpDefault(dialect string, c Column) string {
+	if dialect != MySQL || typeFamily(dialect, c.Type) != "text" || strings.HasPrefix(c.Default, "'") {
+		return c.Default
+	}
+	return "'" + strings.ReplaceAll(c.Default, "'", "''") + "'"
+}
@@ -0,0 +1,190 @@
+package migration
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gertd/go-pluralize"
+)
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// LoadEntities parses the Go files in root/*/entity and builds the schema the
+// entity structs describe. A struct becomes a table when at least one field has
+// a `db:"column"` tag; the table is named by a TableName() method returning a
+// string literal, or the plural snake case of the struct name. A `dbtype` tag
+// overrides the SQL type derived from the Go type.
+func LoadEntities(root, dialect string) (*Schema, error) {
+	files, err := filepath.Glob(filepath.Join(root, "*", "entity", "*.go"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entity files: %w", err)
+	}
+
+	schema := &Schema{Dialect: dialect, Tables: map[string]*Table{}}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+
+		tableNames := tableNameMethods(parsed)
+		ast.Inspect(parsed, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			columns := structColumns(st, dialect)
+			if len(columns) == 0 {
+				return false
+			}
+			name := tableNames[spec.Name.Name]
+			if name == "" {
+				name = defaultTableName(spec.Name.Name)
+			}
+			schema.Tables[name] = &Table{Name: name, Columns: columns}
+			return false
+		})
+	}
+	return schema, nil
+}
+
+// tableNameMethods finds `func (T) TableName() string { return "name" }` declarations
+func tableNameMethods(file *ast.File) map[string]string {
+	names := map[string]string{}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "TableName" || fn.Recv == nil || len(fn.Recv.List) != 1 || fn.Body == nil {
+			continue
+		}
+		recv := fn.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		ident, ok := recv.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		for _, stmt := range fn.Body.List {
+			ret, ok := stmt.(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			if lit, ok := ret.Results[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if value, err := strconv.Unquote(lit.Value); err == nil {
+					names[ident.Name] = value
+				}
+			}
+		}
+	}
+	return names
+}
+
+func defaultTableName(structName string) string {
+	snake := strings.ToLower(camelBoundary.ReplaceAllString(structName, "${1}_${2}"))
+	return pluralize.NewClient().Plural(snake)
+}
+
+func structColumns(st *ast.StructType, dialect string) []Column {
+	var columns []Column
+	for _, field := range st.Fields.List {
+		if field.Tag == nil || len(field.Names) == 0 {
+			continue
+		}
+		raw, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			continue
+		}
+		tag := reflect.StructTag(raw)
+		dbTag, ok := tag.Lookup("db")
+		if !ok {
+			continue
+		}
+		name := strings.Split(dbTag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		column := goColumn(name, field.Type, dialect)
+		if override := tag.Get("dbtype"); override != "" {
+			column.Type = override
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// goColumn maps a Go field type to a column definition
+func goColumn(name string, expr ast.Expr, dialect string) Column {
+	column := Column{Name: name, PrimaryKey: name == "id"}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		column.Nullable = true
+		expr = star.X
+	}
+
+	goType := exprString(expr)
+	mysql := dialect == MySQL
+	switch goType {
+	case "int8", "int16", "uint8", "uint16":
+		column.Type, column.Default = "SMALLINT", "0"
+	case "int32", "uint32":
+		column.Type, column.Default = "INTEGER", "0"
+	case "int", "uint", "int64", "uint64":
+		column.Type, column.Default = "BIGINT", "0"
+	case "float32":
+		column.Type, column.Default = "REAL", "0"
+	case "float64":
+		column.Type, column.Default = "DOUBLE PRECISION", "0"
+		if mysql {
+			column.Type = "DOUBLE"
+		}
+	case "bool":
+		column.Type, column.Default = "BOOLEAN", "FALSE"
+	case "time.Time":
+		column.Type, column.Default = "TIMESTAMP", "CURRENT_TIMESTAMP"
+	case "[]byte", "json.RawMessage":
+		column.Type, column.Nullable = "BYTEA", true
+		if mysql {
+			column.Type = "BLOB"
+		}
+		if goType == "json.RawMessage" {
+			column.Type = "JSONB"
+			if mysql {
+				column.Type = "JSON"
+			}
+		}
+	default:
+		column.Type, column.Default = "VARCHAR(255)", "''"
+	}
+
+	if column.Nullable {
+		column.Default = ""
+	}
+	return column
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + exprString(e.Elt)
+	}
+	return ""
+}
@@ -0,0 +1,377 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/splitters"
+)
+
+// Severity of a lint finding
+type Severity string
+
+const (
+	// SeverityError blocks ApplyMigrations
+	SeverityError Severity = "error"
+	// SeverityWarning is reported but does not block
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single dangerous operation found in a migration
+type Finding struct {
+	File     string
+	Line     int
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s [%s] %s", f.File, f.Line, f.Severity, f.Rule, f.Message)
+}
+
+// lintRule checks one normalised statement
+type lintRule struct {
+	id       string
+	severity Severity
+	dialects []string
+	message  string
+	match    func(stmt string, state *lintState) bool
+}
+
+// lintState carries per-file knowledge between statements
+type lintState struct {
+	createdTables map[string]bool
+}
+
+// isNew reports whether table was created earlier in the same file, in which
+// case locking and rewrite concerns do not apply
+func (s *lintState) isNew(table string) bool {
+	return s.createdTables[table]
+}
+
+var (
+	allowDirective   = regexp.MustCompile(`(?i)--\s*rootx:allow\s+(.+)`)
+	createTableRe    = regexp.MustCompile(`^CREATE\s+(?:TEMP(?:ORARY)?\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w."` + "`" + `]+)`)
+	alterTableRe     = regexp.MustCompile(`^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?([\w."` + "`" + `]+)\s+(.*)$`)
+	createIndexRe    = regexp.MustCompile(`^CREATE\s+(?:UNIQUE\s+)?INDEX\b`)
+	indexTableRe     = regexp.MustCompile(`\bON\s+(?:ONLY\s+)?([\w."` + "`" + `]+)`)
+	addColumnRe      = regexp.MustCompile(`^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?[\w"` + "`" + `]+\s+`)
+	addConstraintRe  = regexp.MustCompile(`^ADD\s+(?:CONSTRAINT\s+\S+\s+)?(?:PRIMARY|UNIQUE|FOREIGN|CHECK|INDEX|KEY|CONSTRAINT)\b`)
+	alterTypeRe      = regexp.MustCompile(`^ALTER\s+(?:COLUMN\s+)?\S+\s+(?:SET\s+DATA\s+)?TYPE\b`)
+	setNotNullRe     = regexp.MustCompile(`^ALTER\s+(?:COLUMN\s+)?\S+\s+SET\s+NOT\s+NULL\b`)
+	mysqlModifyRe    = regexp.MustCompile(`^(?:MODIFY|CHANGE)\b`)
+	mysqlAddIndexRe  = regexp.MustCompile(`^ADD\s+(?:UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?(?:INDEX|KEY)\b`)
+	dropColumnRe     = regexp.MustCompile(`^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?[\w"` + "`" + `]+`)
+	dropConstraintRe = regexp.MustCompile(`^DROP\s+(?:CONSTRAINT|INDEX|KEY|PRIMARY|FOREIGN|CHECK)\b`)
+	renameRe         = regexp.MustCompile(`^RENAME\b`)
+	dropTableRe      = regexp.MustCompile(`^DROP\s+TABLE\b`)
+	whitespaceRe     = regexp.MustCompile(`\s+`)
+)
+
+var lintRules = []lintRule{
+	{
+		id:       "not-null-without-default",
+		severity: SeverityError,
+		dialects: []string{Postgres, MySQL},
+		message:  "adding a NOT NULL column without DEFAULT fails on tables with rows",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, func(clause string) bool {
+				return addColumnRe.MatchString(clause) && !addConstraintRe.MatchString(clause) &&
+					strings.Contains(clause, "NOT NULL") && !strings.Contains(clause, "DEFAULT")
+			})
+		},
+	},
+	{
+		id:       "index-without-concurrently",
+		severity: SeverityError,
+		dialects: []string{Postgres},
+		message:  "CREATE INDEX without CONCURRENTLY blocks writes for the whole build",
+		match: func(stmt string, state *lintState) bool {
+			if !createIndexRe.MatchString(stmt) || strings.Contains(stmt, " CONCURRENTLY ") {
+				return false
+			}
+			match := indexTableRe.FindStringSubmatch(stmt)
+			return match == nil || !state.isNew(normalizeIdent(match[1]))
+		},
+	},
+	{
+		id:       "index-not-online",
+		severity: SeverityWarning,
+		dialects: []string{MySQL},
+		message:  "index builds should declare ALGORITHM=INPLACE, LOCK=NONE to stay online",
+		match: func(stmt string, state *lintState) bool {
+			online := strings.Contains(stmt, "LOCK=NONE") || strings.Contains(stmt, "LOCK = NONE")
+			if createIndexRe.MatchString(stmt) {
+				match := indexTableRe.FindStringSubmatch(stmt)
+				return !online && (match == nil || !state.isNew(normalizeIdent(match[1])))
+			}
+			return !online && anyAlterClause(stmt, state, mysqlAddIndexRe.MatchString)
+		},
+	},
+	{
+		id:       "alter-column-type",
+		severity: SeverityError,
+		dialects: []string{Postgres},
+		message:  "changing a column type rewrites the table under an exclusive lock",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, alterTypeRe.MatchString)
+		},
+	},
+	{
+		id:       "modify-column",
+		severity: SeverityWarning,
+		dialects: []string{MySQL},
+		message:  "MODIFY/CHANGE COLUMN may copy the whole table",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, mysqlModifyRe.MatchString)
+		},
+	},
+	{
+		id:       "set-not-null",
+		severity: SeverityWarning,
+		dialects: []string{Postgres},
+		message:  "SET NOT NULL scans the table under an exclusive lock; add a NOT VALID check constraint first",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, setNotNullRe.MatchString)
+		},
+	},
+	{
+		id:       "foreign-key-not-valid",
+		severity: SeverityWarning,
+		dialects: []string{Postgres},
+		message:  "adding a FOREIGN KEY without NOT VALID locks both tables while validating",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, func(clause string) bool {
+				return strings.HasPrefix(clause, "ADD ") && strings.Contains(clause, "FOREIGN KEY") &&
+					!strings.Contains(clause, "NOT VALID")
+			})
+		},
+	},
+	{
+		id:       "drop-column",
+		severity: SeverityWarning,
+		dialects: []string{Postgres, MySQL},
+		message:  "dropping a column breaks running code that still reads it",
+		match: func(stmt string, state *lintState) bool {
+			return anyAlterClause(stmt, state, func(clause string) bool {
+				return dropColumnRe.MatchString(clause) && !dropConstraintRe.MatchString(clause)
+			})
+		},
+	},
+	{
+		id:       "rename",
+		severity: SeverityWarning,
+		dialects: []string{Postgres, MySQL},
+		message:  "renaming a table or column breaks running code that uses the old name",
+		match: func(stmt string, state *lintState) bool {
+			return strings.HasPrefix(stmt, "RENAME TABLE ") || anyAlterClause(stmt, state, renameRe.MatchString)
+		},
+	},
+	{
+		id:       "drop-table",
+		severity: SeverityWarning,
+		dialects: []string{Postgres, MySQL},
+		message:  "dropping a table is irreversible",
+		match: func(stmt string, state *lintState) bool {
+			return dropTableRe.MatchString(stmt)
+		},
+	},
+}
+
+// anyAlterClause applies check to each top-level clause of an ALTER TABLE on an existing table
+func anyAlterClause(stmt string, state *lintState, check func(clause string) bool) bool {
+	match := alterTableRe.FindStringSubmatch(stmt)
+	if match == nil || state.isNew(normalizeIdent(match[1])) {
+		return false
+	}
+	for _, clause := range splitTopLevel(match[2]) {
+		if check(strings.TrimSpace(clause)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTopLevel splits on commas that are not nested in parentheses
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func normalizeIdent(ident string) string {
+	ident = strings.Trim(ident, "\"`")
+	if i := strings.LastIndex(ident, "."); i >= 0 {
+		ident = ident[i+1:]
+	}
+	return strings.ToLower(strings.Trim(ident, "\"`"))
+}
+
+// LintDir lints every .sql file in dir for the given dialect
+func LintDir(dir, dialect string) ([]Finding, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sql" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var findings []Finding
+	for _, name := range names {
+		filePath := filepath.Join(dir, name)
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+		fileFindings, err := LintSQL(filePath, string(content), dialect)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fileFindings...)
+	}
+	return findings, nil
+}
+
+// LintSQL lints a single migration script. A statement can opt out of rules with a
+// "-- rootx:allow rule-id[, rule-id]" comment (or "-- rootx:allow all") above it.
+func LintSQL(file, content, dialect string) ([]Finding, error) {
+	statements, err := splitters.SplitSQL(content, ";", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split %s: %w", file, err)
+	}
+
+	state := &lintState{createdTables: map[string]bool{}}
+	var findings []Finding
+	line := 1
+	for _, raw := range statements {
+		startLine := line + leadingLines(raw)
+		line += strings.Count(raw, "\n")
+		if isBlankStatement(raw) {
+			continue
+		}
+
+		stmt := normalizeStatement(raw)
+		if match := createTableRe.FindStringSubmatch(stmt); match != nil {
+			state.createdTables[normalizeIdent(match[1])] = true
+			continue
+		}
+
+		allowed := allowedRules(raw)
+		for _, rule := range lintRules {
+			if !containsFold(rule.dialects, dialect) || allowed["ALL"] || allowed[strings.ToUpper(rule.id)] {
+				continue
+			}
+			if rule.match(stmt, state) {
+				findings = append(findings, Finding{
+					File:     file,
+					Line:     startLine,
+					Rule:     rule.id,
+					Severity: rule.severity,
+					Message:  rule.message,
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// HasErrors reports whether any finding has error severity
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeStatement strips comments, collapses whitespace and upper-cases the
+// statement. Quoted literals and identifiers are kept as they are, so a "--"
+// inside 'a--b' does not start a comment.
+func normalizeStatement(raw string) string {
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := len(raw)
+			if j := strings.IndexByte(raw[i+1:], c); j >= 0 {
+				end = i + 1 + j + 1
+			}
+			b.WriteString(raw[i:end])
+			i = end - 1
+		case strings.HasPrefix(raw[i:], "--"):
+			if j := strings.IndexByte(raw[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(raw)
+			}
+			b.WriteByte(' ')
+		case strings.HasPrefix(raw[i:], "/*"):
+			if j := strings.Index(raw[i+2:], "*/"); j >= 0 {
+				i += 2 + j + 1
+			} else {
+				i = len(raw)
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	stmt := whitespaceRe.ReplaceAllString(b.String(), " ")
+	return strings.ToUpper(strings.TrimSpace(stmt))
+}
+
+// leadingLines counts the lines before the first SQL line of a statement
+func leadingLines(raw string) int {
+	for i, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return i
+		}
+	}
+	return 0
+}
+
+func allowedRules(raw string) map[string]bool {
+	allowed := map[string]bool{}
+	for _, match := range allowDirective.FindAllStringSubmatch(raw, -1) {
+		for _, id := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' }) {
+			allowed[strings.ToUpper(id)] = true
+		}
+	}
+	return allowed
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package migration
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		sql     string
+		rules   []string
+	}{
+		{
+			name:    "not null without default",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ADD COLUMN age INTEGER NOT NULL;",
+			rules:   []string{"not-null-without-default"},
+		},
+		{
+			name:    "not null with default",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ADD COLUMN age INTEGER NOT NULL DEFAULT 0;",
+		},
+		{
+			name:    "index without concurrently",
+			dialect: Postgres,
+			sql:     "CREATE INDEX idx_users_name ON users (name);",
+			rules:   []string{"index-without-concurrently"},
+		},
+		{
+			name:    "index concurrently",
+			dialect: Postgres,
+			sql:     "CREATE INDEX CONCURRENTLY idx_users_name ON users (name);",
+		},
+		{
+			name:    "table created in the same file",
+			dialect: Postgres,
+			sql: "CREATE TABLE IF NOT EXISTS users (id SERIAL PRIMARY KEY);\n" +
+				"CREATE INDEX idx_users_id ON users (id);\n" +
+				"ALTER TABLE users ADD COLUMN age INTEGER NOT NULL;",
+		},
+		{
+			name:    "mysql index not online",
+			dialect: MySQL,
+			sql:     "ALTER TABLE users ADD INDEX idx_name (name);",
+			rules:   []string{"index-not-online"},
+		},
+		{
+			name:    "mysql index online",
+			dialect: MySQL,
+			sql:     "ALTER TABLE users ADD INDEX idx_name (name), ALGORITHM=INPLACE, LOCK=NONE;",
+		},
+		{
+			name:    "postgres rules skipped on mysql",
+			dialect: MySQL,
+			sql:     "ALTER TABLE users ALTER COLUMN age TYPE BIGINT;",
+		},
+		{
+			name:    "alter column type",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ALTER COLUMN age TYPE BIGINT;",
+			rules:   []string{"alter-column-type"},
+		},
+		{
+			name:    "mysql modify column",
+			dialect: MySQL,
+			sql:     "ALTER TABLE users MODIFY age BIGINT;",
+			rules:   []string{"modify-column"},
+		},
+		{
+			name:    "set not null",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ALTER COLUMN age SET NOT NULL;",
+			rules:   []string{"set-not-null"},
+		},
+		{
+			name:    "foreign key without not valid",
+			dialect: Postgres,
+			sql:     "ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id);",
+			rules:   []string{"foreign-key-not-valid"},
+		},
+		{
+			name:    "foreign key not valid",
+			dialect: Postgres,
+			sql:     "ALTER TABLE orders ADD CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users (id) NOT VALID;",
+		},
+		{
+			name:    "drop column",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users DROP COLUMN age;",
+			rules:   []string{"drop-column"},
+		},
+		{
+			name:    "drop constraint is not a column",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users DROP CONSTRAINT users_age_check;",
+		},
+		{
+			name:    "rename and drop table",
+			dialect: MySQL,
+			sql:     "RENAME TABLE users TO people;\nDROP TABLE accounts;",
+			rules:   []string{"rename", "drop-table"},
+		},
+		{
+			name:    "several clauses in one statement",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ADD COLUMN a INT NOT NULL, DROP COLUMN b;",
+			rules:   []string{"not-null-without-default", "drop-column"},
+		},
+		{
+			name:    "allow directive",
+			dialect: Postgres,
+			sql:     "-- rootx:allow drop-column\nALTER TABLE users DROP COLUMN age;",
+		},
+		{
+			name:    "allow all",
+			dialect: Postgres,
+			sql:     "-- rootx:allow all\nALTER TABLE users ALTER COLUMN age TYPE BIGINT, DROP COLUMN b;",
+		},
+		{
+			name:    "dashes inside a string literal",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users ADD COLUMN code TEXT DEFAULT 'a--b', ADD COLUMN age INT NOT NULL;",
+			rules:   []string{"not-null-without-default"},
+		},
+		{
+			name:    "block comment",
+			dialect: Postgres,
+			sql:     "ALTER TABLE users /* DROP COLUMN age */ ADD COLUMN age INT NULL;",
+		},
+		{
+			name:    "comments only",
+			dialect: Postgres,
+			sql:     "-- nothing to see\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := LintSQL("m.sql", tt.sql, tt.dialect)
+			if err != nil {
+				t.Fatalf("LintSQL() error = %v", err)
+			}
+			var rules []string
+			for _, f := range findings {
+				rules = append(rules, f.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("LintSQL() rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestLintSQLLineAndSeverity(t *testing.T) {
+	sql := "-- first\nCREATE TABLE a (id INT);\n\n-- widen\nALTER TABLE users\n  ALTER COLUMN age TYPE BIGINT;\nALTER TABLE users DROP COLUMN b;"
+	findings, err := LintSQL("m.sql", sql, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2: %v", len(findings), findings)
+	}
+	if findings[0].Line != 5 || findings[0].Severity != SeverityError {
+		t.Errorf("first finding = %v, want line 5 error", findings[0])
+	}
+	if findings[1].Line != 7 || findings[1].Severity != SeverityWarning {
+		t.Errorf("second finding = %v, want line 7 warning", findings[1])
+	}
+	if !HasErrors(findings) || HasErrors(findings[1:]) {
+		t.Error("HasErrors() does not follow severities")
+	}
+}
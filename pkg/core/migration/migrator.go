@@ -0,0 +1,328 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MigrationsTable records which migration files have been applied
+	MigrationsTable = "rootx_migrations"
+	// BaselineDirective marks a squashed migration. It is only executed on an
+	// empty database; databases with earlier history record it as applied once
+	// every migration listed by ReplacesDirective is applied.
+	BaselineDirective = "-- rootx:baseline"
+	// ReplacesDirective names, one per line, the migrations a baseline replaces
+	ReplacesDirective = "-- rootx:replaces"
+	// LockName is the database lock held while migrations or seeders run, so
+	// replicas starting together apply them once while the others wait
+	LockName = "rootx_migrations"
+)
+
+var (
+	baselineDirective = regexp.MustCompile(`(?im)^--\s*rootx:baseline\s*$`)
+	replacesDirective = regexp.MustCompile(`(?im)^--\s*rootx:replaces\s+(\S+)\s*$`)
+)
+
+// ErrUntracked is returned when a database has tables but no migration
+// history, e.g. one migrated before rootx recorded applied files. Running
+// every migration again could fail or duplicate data.
+var ErrUntracked = errors.New("database has tables but no " + MigrationsTable + " history; " +
+	"run `rootx migrate baseline` to record the migrations it already has")
+
+// Migration is a single SQL migration file
+type Migration struct {
+	Name     string // file name without extension, used as the tracking key
+	Path     string
+	SQL      string
+	Baseline bool
+	Replaces []string // migrations a baseline stands in for
+}
+
+// MigrateResult reports what RunMigrations did
+type MigrateResult struct {
+	Applied  []string
+	Recorded []string // baselines recorded without being executed
+}
+
+// sqlFile is a .sql file read from disk
+type sqlFile struct {
+	name    string
+	path    string
+	content string
+}
+
+// loadSQLFiles reads all .sql files from dir in name order
+func loadSQLFiles(dir string) ([]sqlFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	var files []sqlFile
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		filePath := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+		}
+		files = append(files, sqlFile{
+			name:    strings.TrimSuffix(entry.Name(), ".sql"),
+			path:    filePath,
+			content: string(content),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// LoadMigrations reads all .sql files from dir in name order
+func LoadMigrations(dir string) ([]Migration, error) {
+	files, err := loadSQLFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		m := Migration{
+			Name:     file.name,
+			Path:     file.path,
+			SQL:      file.content,
+			Baseline: baselineDirective.MatchString(file.content),
+		}
+		for _, match := range replacesDirective.FindAllStringSubmatch(file.content, -1) {
+			m.Replaces = append(m.Replaces, match[1])
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, nil
+}
+
+// AppliedMigrations returns the names recorded in MigrationsTable. It fails
+// with ErrUntracked on a database that has tables but no history.
+func AppliedMigrations(ctx context.Context, conn Conn) (map[string]bool, error) {
+	if err := checkTracked(ctx, conn); err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	names, err := conn.QueryStrings(ctx, "SELECT name FROM "+MigrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[string]bool, len(names))
+	for _, name := range names {
+		applied[name] = true
+	}
+	return applied, nil
+}
+
+// PendingMigrations returns the migrations in dir that have not been applied yet
+func PendingMigrations(ctx context.Context, conn Conn, dir string) ([]Migration, error) {
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := AppliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Name] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// RunMigrations applies the pending migrations in dir and records them in MigrationsTable.
+// It holds LockName for the whole run.
+func RunMigrations(ctx context.Context, conn Conn, dir string) (*MigrateResult, error) {
+	unlock, err := conn.Lock(ctx, LockName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := AppliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MigrateResult{}
+	for _, m := range migrations {
+		if applied[m.Name] {
+			continue
+		}
+
+		if m.Baseline && len(applied) > 0 {
+			// The database already has history; the baseline only stands in
+			// for files it ran before, so all of them must be applied
+			if err := checkReplaced(m, applied); err != nil {
+				return result, err
+			}
+			if err := MarkApplied(ctx, conn, m.Name); err != nil {
+				return result, err
+			}
+			result.Recorded = append(result.Recorded, m.Name)
+		} else {
+			if err := applyMigration(ctx, conn, m); err != nil {
+				return result, err
+			}
+			result.Applied = append(result.Applied, m.Name)
+		}
+		applied[m.Name] = true
+	}
+	return result, nil
+}
+
+// applyMigration runs m and records it in one transaction, so a failure
+// never leaves it applied but unrecorded. Scripts that cannot run in a
+// transaction (CREATE INDEX CONCURRENTLY) are recorded once they succeed.
+func applyMigration(ctx context.Context, conn Conn, m Migration) error {
+	run := func(conn Conn) error {
+		if err := execScript(ctx, conn, m.SQL); err != nil {
+			return fmt.Errorf("failed to execute file %s: %w", m.Path, err)
+		}
+		return MarkApplied(ctx, conn, m.Name)
+	}
+	if runsOutsideTx(conn.Dialect(), m.SQL) {
+		return run(conn)
+	}
+	return conn.Tx(ctx, run)
+}
+
+// checkReplaced fails unless every migration the baseline replaces is applied
+func checkReplaced(m Migration, applied map[string]bool) error {
+	if len(m.Replaces) == 0 {
+		return fmt.Errorf("baseline %s does not list the migrations it replaces; "+
+			"record it with `rootx migrate baseline --to %s` once the database matches it", m.Name, m.Name)
+	}
+	for _, name := range m.Replaces {
+		if !applied[name] {
+			return fmt.Errorf("database is behind baseline %s: %s is not applied; "+
+				"apply the squashed migrations before this one", m.Name, name)
+		}
+	}
+	return nil
+}
+
+// Baseline records the migrations in dir up to and including upTo (all of
+// them when upTo is empty) as applied without running them. It is meant for
+// databases whose schema already matches those files.
+func Baseline(ctx context.Context, conn Conn, dir, upTo string) ([]string, error) {
+	unlock, err := conn.Lock(ctx, LockName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	if upTo != "" {
+		found := false
+		for _, m := range migrations {
+			found = found || m.Name == upTo
+		}
+		if !found {
+			return nil, fmt.Errorf("migration %s not found in %s", upTo, dir)
+		}
+	}
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	names, err := conn.QueryStrings(ctx, "SELECT name FROM "+MigrationsTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[string]bool, len(names))
+	for _, name := range names {
+		applied[name] = true
+	}
+
+	var recorded []string
+	for _, m := range migrations {
+		if upTo != "" && m.Name > upTo {
+			break
+		}
+		if applied[m.Name] {
+			continue
+		}
+		if err := MarkApplied(ctx, conn, m.Name); err != nil {
+			return recorded, err
+		}
+		recorded = append(recorded, m.Name)
+	}
+	return recorded, nil
+}
+
+// checkTracked returns ErrUntracked when MigrationsTable is missing but the
+// database already holds other tables
+func checkTracked(ctx context.Context, conn Conn) error {
+	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema()"
+	if conn.Dialect() == MySQL {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()"
+	}
+	tables, err := conn.QueryStrings(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	untracked := false
+	for _, table := range tables {
+		if table == MigrationsTable {
+			return nil
+		}
+		untracked = untracked || !isTrackingTable(table)
+	}
+	if untracked {
+		return ErrUntracked
+	}
+	return nil
+}
+
+// MarkApplied records a migration as applied without running it
+func MarkApplied(ctx context.Context, conn Conn, name string) error {
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (name) VALUES (%s)", MigrationsTable, placeholder(conn.Dialect(), 1))
+	if err := conn.Exec(ctx, insert, name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+	return nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn Conn) error {
+	query := "CREATE TABLE IF NOT EXISTS " + MigrationsTable + " (\n" +
+		"    name VARCHAR(255) PRIMARY KEY,\n" +
+		"    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+		")"
+	if err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", MigrationsTable, err)
+	}
+	return nil
+}
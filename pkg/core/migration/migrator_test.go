@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrationsBaseline(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2024_01_02_000000_baseline.sql": "-- Migration baseline\n" + BaselineDirective + "\n" +
+			ReplacesDirective + " 2024_01_01_000000_create_users\n" +
+			ReplacesDirective + " 2024_01_01_000001_add_age\n\nCREATE TABLE users (id BIGINT);\n",
+		"2024_01_03_000000_add_email.sql": "ALTER TABLE users ADD COLUMN email TEXT;\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Archived migrations in subdirectories are not loaded
+	if err := os.Mkdir(filepath.Join(dir, "squashed_20240102000000"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+	want := []string{"2024_01_01_000000_create_users", "2024_01_01_000001_add_age"}
+	if !migrations[0].Baseline || !reflect.DeepEqual(migrations[0].Replaces, want) {
+		t.Errorf("baseline = %v %v, want true %v", migrations[0].Baseline, migrations[0].Replaces, want)
+	}
+	if migrations[1].Baseline || migrations[1].Replaces != nil {
+		t.Errorf("%s parsed as a baseline", migrations[1].Name)
+	}
+}
+
+func TestCheckReplaced(t *testing.T) {
+	baseline := Migration{Name: "b", Baseline: true, Replaces: []string{"m1", "m2"}}
+	tests := []struct {
+		name      string
+		migration Migration
+		applied   map[string]bool
+		wantErr   bool
+	}{
+		{name: "all applied", migration: baseline, applied: map[string]bool{"m1": true, "m2": true}},
+		{name: "database behind", migration: baseline, applied: map[string]bool{"m1": true}, wantErr: true},
+		{name: "unrelated history", migration: baseline, applied: map[string]bool{"other": true}, wantErr: true},
+		{name: "no replaces list", migration: Migration{Name: "b", Baseline: true}, applied: map[string]bool{"m1": true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReplaced(tt.migration, tt.applied)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkReplaced() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunMigrationsRecordsInSameTx(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2024_01_01_000000_create_users.sql": "CREATE TABLE users (id BIGINT, name TEXT);\n",
+		"2024_01_02_000000_index_name.sql":   "CREATE INDEX CONCURRENTLY idx_users_name ON users (name);\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn := newFakeConn(Postgres)
+	result, err := RunMigrations(context.Background(), conn, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2024_01_01_000000_create_users", "2024_01_02_000000_index_name"}; !reflect.DeepEqual(result.Applied, want) {
+		t.Errorf("Applied = %v, want %v", result.Applied, want)
+	}
+
+	inTx := map[string]bool{}
+	for _, call := range *conn.execs {
+		if strings.HasPrefix(call.query, "INSERT INTO "+MigrationsTable) {
+			inTx["record"] = inTx["record"] || call.inTx
+			continue
+		}
+		switch {
+		case strings.HasPrefix(call.query, "CREATE TABLE users"):
+			inTx["create"] = call.inTx
+		case strings.HasPrefix(call.query, "CREATE INDEX CONCURRENTLY"):
+			inTx["index"] = call.inTx
+		}
+	}
+	if want := map[string]bool{"create": true, "index": false, "record": true}; !reflect.DeepEqual(inTx, want) {
+		t.Errorf("in transaction = %v, want %v", inTx, want)
+	}
+
+	// A failed record rolls the script back with it
+	conn = newFakeConn(Postgres)
+	conn.failOn = "INSERT INTO " + MigrationsTable
+	if _, err := RunMigrations(context.Background(), conn, dir); err == nil {
+		t.Fatal("RunMigrations() error = nil, want the record failure")
+	}
+	for _, call := range *conn.execs {
+		if strings.HasPrefix(call.query, "CREATE TABLE users") {
+			t.Errorf("script %q kept although its record failed", call.query)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Column describes a table column
+type Column struct {
+	Name          string
+	Type          string
+	Nullable      bool
+	Default       string
+	PrimaryKey    bool
+	AutoIncrement bool
+}
+
+// Table describes a table and its columns in declaration order
+type Table struct {
+	Name       string
+	Columns    []Column
+	PrimaryKey []string
+}
+
+// Column returns the named column or nil
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Schema is a set of tables keyed by name plus their secondary indexes
+type Schema struct {
+	Dialect string
+	Tables  map[string]*Table
+	Indexes []Index
+}
+
+// Index is a secondary index together with the statement that creates it
+type Index struct {
+	Table string
+	Name  string
+	SQL   string
+}
+
+// TableNames returns the table names in sorted order
+func (s *Schema) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+const pgColumnsQuery = `SELECT c.relname || '|' || a.attname || '|' || format_type(a.atttypid, a.atttypmod) || '|' ||
+	CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END || '|' ||
+	CASE WHEN a.attidentity <> '' OR COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%' THEN 'auto_increment' ELSE '' END || '|' ||
+	COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = current_schema() AND c.relkind = 'r' AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY c.relname, a.attnum`
+
+const mysqlColumnsQuery = `SELECT CONCAT_WS('|', table_name, column_name, column_type, is_nullable, extra, COALESCE(column_default, ''))
+FROM information_schema.columns
+WHERE table_schema = DATABASE()
+ORDER BY table_name, ordinal_position`
+
+const pgPrimaryKeysQuery = `SELECT tc.table_name || '|' || kcu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema()
+ORDER BY tc.table_name, kcu.ordinal_position`
+
+const mysqlPrimaryKeysQuery = `SELECT CONCAT(table_name, '|', column_name)
+FROM information_schema.key_column_usage
+WHERE constraint_name = 'PRIMARY' AND table_schema = DATABASE()
+ORDER BY table_name, ordinal_position`
+
+const pgIndexesQuery = `SELECT i.tablename || '|' || i.indexname || '|' || i.indexdef
+FROM pg_indexes i
+WHERE i.schemaname = current_schema()
+	AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conname = i.indexname AND c.contype = 'p')
+ORDER BY i.tablename, i.indexname`
+
+const mysqlIndexesQuery = `SELECT CONCAT(table_name, '|', index_name, '|CREATE ', IF(non_unique = 0, 'UNIQUE ', ''), 'INDEX ', index_name,
+	' ON ', table_name, ' (', GROUP_CONCAT(column_name ORDER BY seq_in_index SEPARATOR ', '), ')')
+FROM information_schema.statistics
+WHERE table_schema = DATABASE() AND index_name <> 'PRIMARY'
+GROUP BY table_name, index_name, non_unique
+ORDER BY table_name, index_name`
+
+// LoadSchema reads the tables, columns, primary keys and indexes of the connected database
+func LoadSchema(ctx context.Context, conn Conn) (*Schema, error) {
+	columnsQuery, keysQuery, indexesQuery := pgColumnsQuery, pgPrimaryKeysQuery, pgIndexesQuery
+	if conn.Dialect() == MySQL {
+		columnsQuery, keysQuery, indexesQuery = mysqlColumnsQuery, mysqlPrimaryKeysQuery, mysqlIndexesQuery
+	}
+
+	rows, err := conn.QueryStrings(ctx, columnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	schema := &Schema{Dialect: conn.Dialect(), Tables: map[string]*Table{}}
+	for _, row := range rows {
+		parts := strings.SplitN(row, "|", 6)
+		if len(parts) < 6 {
+			continue
+		}
+		table, ok := schema.Tables[parts[0]]
+		if !ok {
+			table = &Table{Name: parts[0]}
+			schema.Tables[parts[0]] = table
+		}
+		column := Column{
+			Name:          parts[1],
+			Type:          parts[2],
+			Nullable:      parts[3] == "YES",
+			AutoIncrement: strings.Contains(strings.ToLower(parts[4]), "auto_increment"),
+			Default:       parts[5],
+		}
+		if column.AutoIncrement {
+			column.Default = ""
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	keys, err := conn.QueryStrings(ctx, keysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary keys: %w", err)
+	}
+	for _, row := range keys {
+		parts := strings.SplitN(row, "|", 2)
+		if table, ok := schema.Tables[parts[0]]; ok && len(parts) == 2 {
+			table.PrimaryKey = append(table.PrimaryKey, parts[1])
+		}
+	}
+	for _, table := range schema.Tables {
+		if len(table.PrimaryKey) == 1 {
+			if column := table.Column(table.PrimaryKey[0]); column != nil {
+				column.PrimaryKey = true
+			}
+		}
+	}
+
+	indexes, err := conn.QueryStrings(ctx, indexesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes: %w", err)
+	}
+	for _, row := range indexes {
+		parts := strings.SplitN(row, "|", 3)
+		if len(parts) == 3 {
+			schema.Indexes = append(schema.Indexes, Index{Table: parts[0], Name: parts[1], SQL: parts[2]})
+		}
+	}
+	return schema, nil
+}
+
+// pgUnsupportedQuery lists objects LoadSchema does not capture: foreign key,
+// check, unique and exclusion constraints, enums, domains and composite types,
+// views, partitioned tables, standalone sequences, triggers and functions
+const pgUnsupportedQuery = `SELECT CASE c.contype WHEN 'f' THEN 'foreign key' WHEN 'c' THEN 'check constraint'
+		WHEN 'u' THEN 'unique constraint' ELSE 'exclusion constraint' END || ' ' || r.relname || '.' || c.conname
+FROM pg_constraint c
+JOIN pg_class r ON r.oid = c.conrelid
+JOIN pg_namespace n ON n.oid = r.relnamespace
+WHERE n.nspname = current_schema() AND c.contype IN ('f', 'c', 'u', 'x')
+	AND r.relname NOT IN ('rootx_migrations', 'rootx_seeders')
+UNION ALL
+SELECT CASE t.typtype WHEN 'e' THEN 'enum ' WHEN 'd' THEN 'domain ' ELSE 'type ' END || t.typname
+FROM pg_type t
+JOIN pg_namespace n ON n.oid = t.typnamespace
+LEFT JOIN pg_class r ON r.oid = t.typrelid
+WHERE n.nspname = current_schema() AND (t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND r.relkind = 'c'))
+UNION ALL
+SELECT CASE r.relkind WHEN 'v' THEN 'view ' WHEN 'm' THEN 'materialized view ' WHEN 'p' THEN 'partitioned table '
+		ELSE 'sequence ' END || r.relname
+FROM pg_class r
+JOIN pg_namespace n ON n.oid = r.relnamespace
+WHERE n.nspname = current_schema() AND (r.relkind IN ('v', 'm', 'p') OR (r.relkind = 'S'
+	AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = r.oid AND d.deptype IN ('a', 'i'))))
+UNION ALL
+SELECT 'trigger ' || r.relname || '.' || g.tgname
+FROM pg_trigger g
+JOIN pg_class r ON r.oid = g.tgrelid
+JOIN pg_namespace n ON n.oid = r.relnamespace
+WHERE n.nspname = current_schema() AND NOT g.tgisinternal
+UNION ALL
+SELECT 'function ' || p.proname
+FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = current_schema()
+ORDER BY 1`
+
+// mysqlUnsupportedQuery lists objects LoadSchema does not capture: foreign key
+// and check constraints, views, ON UPDATE and generated columns, triggers and routines
+const mysqlUnsupportedQuery = `SELECT CONCAT(LOWER(constraint_type), ' ', table_name, '.', constraint_name)
+FROM information_schema.table_constraints
+WHERE table_schema = DATABASE() AND constraint_type IN ('FOREIGN KEY', 'CHECK')
+UNION ALL
+SELECT CONCAT('view ', table_name)
+FROM information_schema.views
+WHERE table_schema = DATABASE()
+UNION ALL
+SELECT CONCAT(IF(extra LIKE '%GENERATED%', 'generated column ', 'on update column '), table_name, '.', column_name)
+FROM information_schema.columns
+WHERE table_schema = DATABASE() AND (LOWER(extra) LIKE '%on update%' OR extra LIKE '%GENERATED%')
+UNION ALL
+SELECT CONCAT('trigger ', event_object_table, '.', trigger_name)
+FROM information_schema.triggers
+WHERE trigger_schema = DATABASE()
+UNION ALL
+SELECT CONCAT(LOWER(routine_type), ' ', routine_name)
+FROM information_schema.routines
+WHERE routine_schema = DATABASE()
+ORDER BY 1`
+
+// UnsupportedObjects describes the objects in the connected database that
+// LoadSchema and DumpSQL leave out. A dump is only a faithful copy of the
+// schema when the list is empty.
+func UnsupportedObjects(ctx context.Context, conn Conn) ([]string, error) {
+	query := pgUnsupportedQuery
+	if conn.Dialect() == MySQL {
+		query = mysqlUnsupportedQuery
+	}
+	objects, err := conn.QueryStrings(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schema objects: %w", err)
+	}
+	return objects, nil
+}
+
+// typeFamily reduces a SQL type to a comparable family so that equivalent
+// spellings (INTEGER vs int4, VARCHAR(100) vs character varying) do not diff
+func typeFamily(dialect, sqlType string) string {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	if i := strings.Index(t, "("); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	t = strings.TrimSuffix(t, " unsigned")
+
+	switch {
+	case t == "boolean" || t == "bool":
+		if dialect == MySQL {
+			return "int" // MySQL stores BOOLEAN as TINYINT(1)
+		}
+		return "bool"
+	case strings.Contains(t, "int") || strings.Contains(t, "serial"):
+		return "int"
+	case strings.Contains(t, "char") || strings.Contains(t, "text") || t == "enum":
+		return "text"
+	case strings.HasPrefix(t, "timestamp") || t == "datetime":
+		return "time"
+	case t == "date":
+		return "date"
+	case strings.HasPrefix(t, "double") || t == "real" || t == "float" || t == "numeric" || t == "decimal":
+		return "float"
+	case t == "bytea" || strings.Contains(t, "blob") || strings.Contains(t, "binary"):
+		return "bytes"
+	case strings.HasPrefix(t, "json"):
+		return "json"
+	}
+	return t
+}
@@ -0,0 +1,247 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/splitters"
+)
+
+const (
+	// SeedersTable records which seed files have been applied
+	SeedersTable = "rootx_seeders"
+	// ProductionEnv is the APP_ENV value seeders refuse to run in unless forced
+	ProductionEnv = "production"
+)
+
+// ErrProductionSeed is returned when seeding production without Force
+var ErrProductionSeed = errors.New("refusing to run seeders in production without --force")
+
+var (
+	timestampPrefix = regexp.MustCompile(`^\d{4}_\d{2}_\d{2}_\d{6}_`)
+	envDirective    = regexp.MustCompile(`(?i)^--\s*env\s*:(.*)$`)
+)
+
+// Seeder is a single SQL seed file
+type Seeder struct {
+	Name string   // file name without extension, used as the tracking key
+	Path string   // path on disk
+	Envs []string // environments the seeder may run in, empty means all
+	SQL  string
+}
+
+// ShortName returns the seeder name without timestamp prefix and _seeder suffix, e.g. "users"
+func (s Seeder) ShortName() string {
+	name := timestampPrefix.ReplaceAllString(s.Name, "")
+	return strings.TrimSuffix(name, "_seeder")
+}
+
+// AllowedIn reports whether the seeder is tagged for the given environment
+func (s Seeder) AllowedIn(env string) bool {
+	if len(s.Envs) == 0 {
+		return true
+	}
+	for _, e := range s.Envs {
+		if strings.EqualFold(e, env) {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedOptions controls which seeders RunSeeders applies
+type SeedOptions struct {
+	Dir   string   // directory holding the seed files
+	Env   string   // current APP_ENV
+	Only  []string // restrict to these seeders (short or full name)
+	Force bool     // allow running in production
+}
+
+// SeedResult reports what RunSeeders did
+type SeedResult struct {
+	Applied []string
+	Skipped []string
+}
+
+// LoadSeeders reads all .sql files from dir in name order and parses their env directives
+func LoadSeeders(dir string) ([]Seeder, error) {
+	files, err := loadSQLFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	seeders := make([]Seeder, 0, len(files))
+	for _, file := range files {
+		seeders = append(seeders, Seeder{
+			Name: file.name,
+			Path: file.path,
+			Envs: parseEnvs(file.content),
+			SQL:  file.content,
+		})
+	}
+	return seeders, nil
+}
+
+// parseEnvs collects environments from "-- env: development, test" comment lines
+func parseEnvs(content string) []string {
+	var envs []string
+	for _, line := range strings.Split(content, "\n") {
+		match := envDirective.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		for _, env := range strings.Split(match[1], ",") {
+			if env = strings.TrimSpace(env); env != "" {
+				envs = append(envs, env)
+			}
+		}
+	}
+	return envs
+}
+
+// RunSeeders applies pending seeders for opts.Env and records them in SeedersTable.
+// It holds LockName for the whole run.
+func RunSeeders(ctx context.Context, conn Conn, opts SeedOptions) (*SeedResult, error) {
+	if strings.EqualFold(opts.Env, ProductionEnv) && !opts.Force {
+		return nil, ErrProductionSeed
+	}
+
+	unlock, err := conn.Lock(ctx, LockName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	seeders, err := LoadSeeders(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ensureSeedersTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	applied, err := conn.QueryStrings(ctx, "SELECT name FROM "+SeedersTable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied seeders: %w", err)
+	}
+	done := make(map[string]bool, len(applied))
+	for _, name := range applied {
+		done[name] = true
+	}
+
+	result := &SeedResult{}
+	for _, seeder := range seeders {
+		if !matchesOnly(seeder, opts.Only) {
+			continue
+		}
+		if done[seeder.Name] || !seeder.AllowedIn(opts.Env) {
+			result.Skipped = append(result.Skipped, seeder.Name)
+			continue
+		}
+
+		// The seed and its record commit together, so a failed seeder is
+		// neither half applied nor applied but unrecorded
+		err := conn.Tx(ctx, func(tx Conn) error {
+			if err := execScript(ctx, tx, seeder.SQL); err != nil {
+				return fmt.Errorf("failed to execute file %s: %w", seeder.Path, err)
+			}
+			insert := fmt.Sprintf("INSERT INTO %s (name, env) VALUES (%s)", SeedersTable, placeholders(tx.Dialect(), 2))
+			if err := tx.Exec(ctx, insert, seeder.Name, opts.Env); err != nil {
+				return fmt.Errorf("failed to record seeder %s: %w", seeder.Name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Applied = append(result.Applied, seeder.Name)
+	}
+
+	return result, nil
+}
+
+func ensureSeedersTable(ctx context.Context, conn Conn) error {
+	query := "CREATE TABLE IF NOT EXISTS " + SeedersTable + " (\n" +
+		"    name VARCHAR(255) PRIMARY KEY,\n" +
+		"    env VARCHAR(50) NOT NULL,\n" +
+		"    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+		")"
+	if err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", SeedersTable, err)
+	}
+	return nil
+}
+
+func matchesOnly(seeder Seeder, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, name := range only {
+		name = strings.TrimSuffix(strings.TrimSpace(name), ".sql")
+		if name == seeder.Name || name == seeder.ShortName() {
+			return true
+		}
+	}
+	return false
+}
+
+// noTxStatement matches normalised Postgres statements that cannot run inside
+// a transaction block
+var noTxStatement = regexp.MustCompile(`^(?:(?:(?:CREATE(?: UNIQUE)?|DROP) INDEX|REINDEX)\b.*\bCONCURRENTLY\b|(?:VACUUM|CREATE DATABASE|DROP DATABASE|ALTER SYSTEM)\b)`)
+
+// runsOutsideTx reports whether script holds a statement, such as CREATE INDEX
+// CONCURRENTLY, that Postgres refuses to run in a transaction block
+func runsOutsideTx(dialect, script string) bool {
+	if dialect == MySQL {
+		return false
+	}
+	statements, err := splitters.SplitSQL(script, ";", true)
+	if err != nil {
+		return false
+	}
+	for _, statement := range statements {
+		if !isBlankStatement(statement) && noTxStatement.MatchString(normalizeStatement(statement)) {
+			return true
+		}
+	}
+	return false
+}
+
+// execScript runs a SQL file. Postgres accepts the whole script at once, but
+// runs a multi-statement Exec as one implicit transaction; scripts that must
+// run outside one, and MySQL scripts, are split on semicolons and sent one
+// statement per call.
+func execScript(ctx context.Context, conn Conn, script string) error {
+	if conn.Dialect() != MySQL && !runsOutsideTx(conn.Dialect(), script) {
+		return conn.Exec(ctx, script)
+	}
+
+	statements, err := splitters.SplitSQL(script, ";", true)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if isBlankStatement(statement) {
+			continue
+		}
+		if err := conn.Exec(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBlankStatement reports whether a statement holds only whitespace and comments
+func isBlankStatement(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// execCall is a statement run on a fakeConn and whether it ran in a transaction
+type execCall struct {
+	query string
+	inTx  bool
+}
+
+// fakeConn records statements; queries return tables, and Exec fails on
+// statements containing failOn
+type fakeConn struct {
+	dialect string
+	tables  []string
+	failOn  string
+	execs   *[]execCall
+	inTx    bool
+}
+
+func newFakeConn(dialect string) *fakeConn {
+	return &fakeConn{dialect: dialect, execs: &[]execCall{}}
+}
+
+func (c *fakeConn) Dialect() string { return c.dialect }
+
+func (c *fakeConn) Exec(ctx context.Context, query string, args ...any) error {
+	if c.failOn != "" && strings.Contains(query, c.failOn) {
+		return errors.New("exec failed")
+	}
+	*c.execs = append(*c.execs, execCall{query: strings.TrimSpace(query), inTx: c.inTx})
+	return nil
+}
+
+func (c *fakeConn) QueryStrings(ctx context.Context, query string, args ...any) ([]string, error) {
+	if strings.Contains(query, "information_schema") {
+		return c.tables, nil
+	}
+	return nil, nil
+}
+
+func (c *fakeConn) Lock(ctx context.Context, name string) (func(), error) {
+	return func() {}, nil
+}
+
+// Tx drops the statements of a failed transaction, as a rollback would
+func (c *fakeConn) Tx(ctx context.Context, fn func(tx Conn) error) error {
+	tx := *c
+	tx.inTx = true
+	before := len(*c.execs)
+	if err := fn(&tx); err != nil {
+		*c.execs = (*c.execs)[:before]
+		return err
+	}
+	return nil
+}
+
+func TestExecScriptRunsConcurrentlyAlone(t *testing.T) {
+	script := "CREATE TABLE tags (id BIGINT, name TEXT);\n" +
+		"-- builds without blocking writes\n" +
+		"CREATE INDEX CONCURRENTLY idx_users_name ON users (name);\n"
+	tests := []struct {
+		name    string
+		dialect string
+		script  string
+		want    []string
+	}{
+		{
+			name:    "postgres script in one call",
+			dialect: Postgres,
+			script:  "CREATE TABLE tags (id BIGINT);\nCREATE INDEX idx_tags_id ON tags (id);\n",
+			want:    []string{"CREATE TABLE tags (id BIGINT);\nCREATE INDEX idx_tags_id ON tags (id);"},
+		},
+		{
+			name:    "postgres concurrently split into statements",
+			dialect: Postgres,
+			script:  script,
+			want: []string{
+				"CREATE TABLE tags (id BIGINT, name TEXT)",
+				"-- builds without blocking writes\nCREATE INDEX CONCURRENTLY idx_users_name ON users (name)",
+			},
+		},
+		{
+			name:    "mysql split into statements",
+			dialect: MySQL,
+			script:  "INSERT INTO t VALUES ('a;b');\nINSERT INTO t VALUES ('c');\n",
+			want:    []string{"INSERT INTO t VALUES ('a;b')", "INSERT INTO t VALUES ('c')"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeConn(tt.dialect)
+			if err := execScript(context.Background(), conn, tt.script); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, call := range *conn.execs {
+				got = append(got, call.query)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("executed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunsOutsideTx(t *testing.T) {
+	tests := []struct {
+		script string
+		want   bool
+	}{
+		{script: "CREATE INDEX CONCURRENTLY idx ON users (name);", want: true},
+		{script: "create unique index concurrently idx on users (name);", want: true},
+		{script: "DROP INDEX CONCURRENTLY IF EXISTS idx;", want: true},
+		{script: "REINDEX INDEX CONCURRENTLY idx;", want: true},
+		{script: "VACUUM ANALYZE users;", want: true},
+		{script: "CREATE INDEX idx ON users (name);", want: false},
+		{script: "-- CREATE INDEX CONCURRENTLY idx ON users (name)\nSELECT 1;", want: false},
+		{script: "INSERT INTO notes (body) VALUES ('CREATE INDEX CONCURRENTLY');", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := runsOutsideTx(Postgres, tt.script); got != tt.want {
+			t.Errorf("runsOutsideTx(%q) = %v, want %v", tt.script, got, tt.want)
+		}
+	}
+	if runsOutsideTx(MySQL, "CREATE INDEX CONCURRENTLY idx ON users (name);") {
+		t.Error("runsOutsideTx() = true for MySQL")
+	}
+}
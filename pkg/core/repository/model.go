@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	"github.com/gertd/go-pluralize"
+)
+
+// Column names with built-in behaviour
+const (
+	CreatedAtColumn = "created_at"
+	UpdatedAtColumn = "updated_at"
+	DeletedAtColumn = "deleted_at"
+	StatusColumn    = "status"
+	VersionColumn   = "version"
+)
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	statusType = reflect.TypeOf(coreEntity.Status(""))
+)
+
+// field is a struct field mapped to a column
+type field struct {
+	column string
+	index  []int
+	typ    reflect.Type
+}
+
+// model is the table mapping of an entity type, built from its `db` tags:
+//
+//	ID        uint       `db:"id"`          // primary key (or any column tagged `db:"col,pk"`)
+//	Name      string     `db:"name"`
+//	DeletedAt *time.Time `db:"deleted_at"` // enables soft delete
+//	Version   int        `db:"version"`    // enables optimistic locking
+//	Ignored   string     `db:"-"`
+type model struct {
+	table   string
+	fields  []field
+	byName  map[string]*field
+	pk      *field
+	columns []string
+}
+
+var models sync.Map // reflect.Type -> *model
+
+// tableNamer lets an entity choose its table, as in the generated entity.stub
+type tableNamer interface {
+	TableName() string
+}
+
+// modelOf returns the cached mapping for T
+func modelOf[T any]() (*model, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if cached, ok := models.Load(typ); ok {
+		return cached.(*model), nil
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("repository entity %s must be a struct", typ)
+	}
+
+	m := &model{byName: map[string]*field{}}
+	var zero T
+	if namer, ok := any(zero).(tableNamer); ok {
+		m.table = namer.TableName()
+	} else if namer, ok := any(&zero).(tableNamer); ok {
+		m.table = namer.TableName()
+	} else {
+		snake := strings.ToLower(camelBoundary.ReplaceAllString(typ.Name(), "${1}_${2}"))
+		m.table = pluralize.NewClient().Plural(snake)
+	}
+
+	var pkField string
+	collectFields(typ, nil, func(f field, options []string) {
+		m.fields = append(m.fields, f)
+		for _, option := range options {
+			if option == "pk" {
+				pkField = f.column
+			}
+		}
+	})
+	if len(m.fields) == 0 {
+		return nil, fmt.Errorf("repository entity %s has no db tagged fields", typ)
+	}
+	if pkField == "" {
+		pkField = "id"
+	}
+	for i := range m.fields {
+		f := &m.fields[i]
+		m.byName[f.column] = f
+		m.columns = append(m.columns, f.column)
+	}
+	if m.pk = m.byName[pkField]; m.pk == nil {
+		return nil, fmt.Errorf("repository entity %s has no primary key column %s", typ, pkField)
+	}
+
+	cached, _ := models.LoadOrStore(typ, m)
+	return cached.(*model), nil
+}
+
+// collectFields walks the db tagged fields of typ, descending into embedded structs
+func collectFields(typ reflect.Type, index []int, visit func(field, []string)) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		tag, ok := sf.Tag.Lookup("db")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				collectFields(sf.Type, fieldIndex, visit)
+			}
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if parts[0] == "" || parts[0] == "-" || !sf.IsExported() {
+			continue
+		}
+		visit(field{column: parts[0], index: fieldIndex, typ: sf.Type}, parts[1:])
+	}
+}
+
+func (m *model) has(column string) bool {
+	return m.byName[column] != nil
+}
+
+// softDelete reports whether the entity has a deleted_at column
+func (m *model) softDelete() bool {
+	return m.has(DeletedAtColumn)
+}
+
+// versioned reports whether the entity has an integer version column
+func (m *model) versioned() bool {
+	f := m.byName[VersionColumn]
+	if f == nil {
+		return false
+	}
+	switch f.typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// fieldValue returns the addressable field for column in v
+func (m *model) fieldValue(v reflect.Value, column string) reflect.Value {
+	return v.FieldByIndex(m.byName[column].index)
+}
+
+// scanDest returns pointers to the fields of v for columns
+func (m *model) scanDest(v reflect.Value, columns []string) []any {
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		dest[i] = m.fieldValue(v, column).Addr().Interface()
+	}
+	return dest
+}
+
+// setTime stores now in a time.Time or *time.Time field; other types are left alone
+func setTime(fv reflect.Value, now time.Time) {
+	switch {
+	case fv.Type() == timeType:
+		fv.Set(reflect.ValueOf(now))
+	case fv.Kind() == reflect.Pointer && fv.Type().Elem() == timeType:
+		fv.Set(reflect.ValueOf(&now))
+	}
+}
+
+// setStatus stores status in a coreEntity.Status field; other types are left alone
+func setStatus(fv reflect.Value, status coreEntity.Status) {
+	if fv.Type() == statusType {
+		fv.Set(reflect.ValueOf(status))
+	}
+}
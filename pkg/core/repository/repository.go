@@ -0,0 +1,531 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+)
+
+// ErrNotFound is returned when no row matches
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a versioned entity was changed by someone else
+// since it was read
+var ErrConflict = errors.New("record was modified concurrently")
+
+// Scope narrows a query and can be reused across calls, e.g.
+//
+//	func Active(qb *utilQuery.Builder) { qb.Where("status", utilQuery.OpEq, true) }
+type Scope func(qb *utilQuery.Builder)
+
+// Hooks an entity can implement on its pointer type. They run inside the same
+// transaction as the statement; returning an error aborts the operation.
+type (
+	BeforeCreator interface {
+		BeforeCreate(ctx context.Context) error
+	}
+	AfterCreator interface {
+		AfterCreate(ctx context.Context) error
+	}
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context) error
+	}
+	AfterUpdater interface {
+		AfterUpdate(ctx context.Context) error
+	}
+	BeforeDeleter interface {
+		BeforeDelete(ctx context.Context) error
+	}
+	AfterDeleter interface {
+		AfterDelete(ctx context.Context) error
+	}
+	BeforeRestorer interface {
+		BeforeRestore(ctx context.Context) error
+	}
+	AfterRestorer interface {
+		AfterRestore(ctx context.Context) error
+	}
+)
+
+type trashedMode int
+
+const (
+	withoutTrashed trashedMode = iota
+	withTrashed
+	onlyTrashed
+)
+
+// Repository is a generic CRUD repository for the entity type T, mapped through
+// its `db` struct tags. created_at and updated_at are maintained automatically;
+// a deleted_at column turns Delete into a soft delete and hides deleted rows
+// from queries, and a version column enables optimistic locking. Generated modules embed it in their persistence struct.
+type Repository[T any] struct {
+	app     *app.App
+	model   *model
+	scopes  []Scope
+	trashed trashedMode
+}
+
+// New builds a repository for T
+func New[T any](app *app.App) (*Repository[T], error) {
+	m, err := modelOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	return &Repository[T]{app: app, model: m}, nil
+}
+
+// MustNew is like New but panics if T cannot be mapped
+func MustNew[T any](app *app.App) *Repository[T] {
+	r, err := New[T](app)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r *Repository[T]) clone() *Repository[T] {
+	c := *r
+	c.scopes = append([]Scope(nil), r.scopes...)
+	return &c
+}
+
+// Scoped returns a repository whose queries all apply scopes
+func (r *Repository[T]) Scoped(scopes ...Scope) *Repository[T] {
+	c := r.clone()
+	c.scopes = append(c.scopes, scopes...)
+	return c
+}
+
+// WithTrashed returns a repository whose queries include soft deleted rows
+func (r *Repository[T]) WithTrashed() *Repository[T] {
+	c := r.clone()
+	c.trashed = withTrashed
+	return c
+}
+
+// OnlyTrashed returns a repository whose queries only see soft deleted rows
+func (r *Repository[T]) OnlyTrashed() *Repository[T] {
+	c := r.clone()
+	c.trashed = onlyTrashed
+	return c
+}
+
+// Table returns the table T is stored in
+func (r *Repository[T]) Table() string {
+	return r.model.table
+}
+
+// Query returns a builder over T's table selecting every mapped column, with
+// the columns whitelisted, soft deleted rows excluded and the scopes applied
+func (r *Repository[T]) Query(scopes ...Scope) *utilQuery.Builder {
+	qb := utilQuery.Select(r.model.table, r.model.columns...).
+		Dialect(r.app.Database.Dialect()).
+		Allow(r.model.columns...)
+
+	if r.model.softDelete() {
+		switch r.trashed {
+		case withoutTrashed:
+			qb.Where(DeletedAtColumn, utilQuery.OpIsNull, nil)
+		case onlyTrashed:
+			qb.Where(DeletedAtColumn, utilQuery.OpIsNotNull, nil)
+		}
+	}
+	for _, scope := range r.scopes {
+		scope(qb)
+	}
+	for _, scope := range scopes {
+		scope(qb)
+	}
+	return qb
+}
+
+// Find returns the row with the given primary key, or ErrNotFound
+func (r *Repository[T]) Find(ctx context.Context, id any) (*T, error) {
+	return r.First(ctx, func(qb *utilQuery.Builder) {
+		qb.Where(r.model.pk.column, utilQuery.OpEq, id)
+	})
+}
+
+// First returns the first row matching scopes, or ErrNotFound
+func (r *Repository[T]) First(ctx context.Context, scopes ...Scope) (*T, error) {
+	items, err := r.Fetch(ctx, r.Query(scopes...).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+	return items[0], nil
+}
+
+// List returns every row matching scopes
+func (r *Repository[T]) List(ctx context.Context, scopes ...Scope) ([]*T, error) {
+	return r.Fetch(ctx, r.Query(scopes...))
+}
+
+// Count returns the number of rows matching scopes
+func (r *Repository[T]) Count(ctx context.Context, scopes ...Scope) (int, error) {
+	query, args, err := r.Query(scopes...).BuildCount()
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	if err := r.app.Database.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Paginate returns the page of rows matching scopes selected by the page and
+// limit query parameters
+func (r *Repository[T]) Paginate(req *http.Request, scopes ...Scope) ([]*T, coreEntity.Pagination, error) {
+	qb := r.Query(scopes...)
+	pagination, err := utilQuery.PaginateQuery(req, r.app, qb)
+	if err != nil {
+		return nil, pagination, err
+	}
+	items, err := r.Fetch(req.Context(), qb)
+	return items, pagination, err
+}
+
+// Fetch runs qb, which should come from Query, and scans the rows into T
+func (r *Repository[T]) Fetch(ctx context.Context, qb *utilQuery.Builder) ([]*T, error) {
+	columns := qb.SelectedColumns()
+	for _, column := range columns {
+		if !r.model.has(column) {
+			return nil, fmt.Errorf("column %s is not mapped on %s", column, r.model.table)
+		}
+	}
+
+	query, args, err := qb.Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.app.Database.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*T
+	for rows.Next() {
+		item := new(T)
+		if err := rows.Scan(r.model.scanDest(reflect.ValueOf(item).Elem(), columns)...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// withEntityTx runs fn in a transaction on a copy of entity and copies it back
+// once the transaction succeeds. WithTx may run fn again after a serialization
+// failure; each attempt then starts from the caller's values instead of an
+// already assigned key, bumped version or timestamp. Hooks see the copy.
+func (r *Repository[T]) withEntityTx(ctx context.Context, entity *T, fn func(ctx context.Context, entity *T) error) error {
+	var work T
+	err := r.app.WithTx(ctx, func(ctx context.Context) error {
+		work = *entity
+		return fn(ctx, &work)
+	})
+	if err != nil {
+		return err
+	}
+	*entity = work
+	return nil
+}
+
+// Create inserts entity and stores the generated primary key back into it
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.withEntityTx(ctx, entity, func(ctx context.Context, entity *T) error {
+		if hook, ok := any(entity).(BeforeCreator); ok {
+			if err := hook.BeforeCreate(ctx); err != nil {
+				return err
+			}
+		}
+
+		v := reflect.ValueOf(entity).Elem()
+		now := time.Now()
+		for _, column := range []string{CreatedAtColumn, UpdatedAtColumn} {
+			if r.model.has(column) && r.model.fieldValue(v, column).IsZero() {
+				setTime(r.model.fieldValue(v, column), now)
+			}
+		}
+
+		if r.model.versioned() && r.model.fieldValue(v, VersionColumn).IsZero() {
+			setInt(r.model.fieldValue(v, VersionColumn), 1)
+		}
+
+		pk := r.model.fieldValue(v, r.model.pk.column)
+		var columns, marks []string
+		var args []any
+		for _, f := range r.model.fields {
+			if f.column == r.model.pk.column && pk.IsZero() {
+				continue // generated by the database
+			}
+			columns = append(columns, f.column)
+			marks = append(marks, "?")
+			args = append(args, v.FieldByIndex(f.index).Interface())
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			r.model.table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+
+		if !pk.IsZero() {
+			if _, err := r.app.Database.Exec(ctx, query, args...); err != nil {
+				return err
+			}
+		} else if r.app.Database.Dialect() == database.MySQL {
+			result, err := r.app.Database.Exec(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			setInt(pk, result.LastInsertID)
+		} else {
+			query += " RETURNING " + r.model.pk.column
+			if err := r.app.Database.QueryRow(ctx, query, args...).Scan(pk.Addr().Interface()); err != nil {
+				return err
+			}
+		}
+
+		if hook, ok := any(entity).(AfterCreator); ok {
+			return hook.AfterCreate(ctx)
+		}
+		return nil
+	})
+}
+
+// Update writes every mapped column of entity to its row, or returns ErrNotFound.
+// When T has a version column the row is only written if its version still
+// matches entity's, otherwise ErrConflict is returned; on success the version
+// is incremented in both.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	return r.withEntityTx(ctx, entity, func(ctx context.Context, entity *T) error {
+		if hook, ok := any(entity).(BeforeUpdater); ok {
+			if err := hook.BeforeUpdate(ctx); err != nil {
+				return err
+			}
+		}
+
+		v := reflect.ValueOf(entity).Elem()
+		if r.model.has(UpdatedAtColumn) {
+			setTime(r.model.fieldValue(v, UpdatedAtColumn), time.Now())
+		}
+
+		var sets []string
+		var args []any
+		for _, f := range r.model.fields {
+			if f.column == r.model.pk.column || f.column == CreatedAtColumn || f.column == VersionColumn {
+				continue
+			}
+			sets = append(sets, f.column+" = ?")
+			args = append(args, v.FieldByIndex(f.index).Interface())
+		}
+		guard, guardArgs := r.versionGuard(v)
+		if guard != "" {
+			sets = append(sets, VersionColumn+" = "+VersionColumn+" + 1")
+		}
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?%s", r.model.table, strings.Join(sets, ", "), r.model.pk.column, guard)
+		id := r.model.fieldValue(v, r.model.pk.column).Interface()
+		args = append(append(args, id), guardArgs...)
+
+		if err := r.execVersioned(ctx, v, id, query, args...); err != nil {
+			return err
+		}
+		r.bumpVersion(v)
+
+		if hook, ok := any(entity).(AfterUpdater); ok {
+			return hook.AfterUpdate(ctx)
+		}
+		return nil
+	})
+}
+
+// Delete soft deletes entity when T has a deleted_at column (also setting a
+// coreEntity.Status status column to entity.Deleted), and removes it otherwise
+func (r *Repository[T]) Delete(ctx context.Context, entity *T) error {
+	if !r.model.softDelete() {
+		return r.ForceDelete(ctx, entity)
+	}
+	return r.delete(ctx, entity, func(ctx context.Context, v reflect.Value, id any) error {
+		now := time.Now()
+		sets := []string{DeletedAtColumn + " = ?"}
+		args := []any{now}
+		statusField := r.model.has(StatusColumn) && r.model.byName[StatusColumn].typ == statusType
+		if statusField {
+			sets = append(sets, StatusColumn+" = ?")
+			args = append(args, coreEntity.Deleted)
+		}
+		guard, guardArgs := r.versionGuard(v)
+		if guard != "" {
+			sets = append(sets, VersionColumn+" = "+VersionColumn+" + 1")
+		}
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ? AND %s IS NULL%s",
+			r.model.table, strings.Join(sets, ", "), r.model.pk.column, DeletedAtColumn, guard)
+		if err := r.execVersioned(ctx, v, id, query, append(append(args, id), guardArgs...)...); err != nil {
+			return err
+		}
+		r.bumpVersion(v)
+
+		setTime(r.model.fieldValue(v, DeletedAtColumn), now)
+		if statusField {
+			setStatus(r.model.fieldValue(v, StatusColumn), coreEntity.Deleted)
+		}
+		return nil
+	})
+}
+
+// ForceDelete removes entity's row even when T supports soft delete. Like
+// Update it returns ErrConflict if a versioned row changed since it was read.
+func (r *Repository[T]) ForceDelete(ctx context.Context, entity *T) error {
+	return r.delete(ctx, entity, func(ctx context.Context, v reflect.Value, id any) error {
+		guard, guardArgs := r.versionGuard(v)
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?%s", r.model.table, r.model.pk.column, guard)
+		return r.execVersioned(ctx, v, id, query, append([]any{id}, guardArgs...)...)
+	})
+}
+
+func (r *Repository[T]) delete(ctx context.Context, entity *T, run func(ctx context.Context, v reflect.Value, id any) error) error {
+	return r.withEntityTx(ctx, entity, func(ctx context.Context, entity *T) error {
+		if hook, ok := any(entity).(BeforeDeleter); ok {
+			if err := hook.BeforeDelete(ctx); err != nil {
+				return err
+			}
+		}
+
+		v := reflect.ValueOf(entity).Elem()
+		if err := run(ctx, v, r.model.fieldValue(v, r.model.pk.column).Interface()); err != nil {
+			return err
+		}
+
+		if hook, ok := any(entity).(AfterDeleter); ok {
+			return hook.AfterDelete(ctx)
+		}
+		return nil
+	})
+}
+
+// Restore undoes a soft delete, setting a coreEntity.Status status column back
+// to entity.Active. Like Update it returns ErrConflict if a versioned row
+// changed since it was read.
+func (r *Repository[T]) Restore(ctx context.Context, entity *T) error {
+	if !r.model.softDelete() {
+		return fmt.Errorf("%s does not support soft delete", r.model.table)
+	}
+
+	return r.withEntityTx(ctx, entity, func(ctx context.Context, entity *T) error {
+		if hook, ok := any(entity).(BeforeRestorer); ok {
+			if err := hook.BeforeRestore(ctx); err != nil {
+				return err
+			}
+		}
+
+		v := reflect.ValueOf(entity).Elem()
+		sets := []string{DeletedAtColumn + " = NULL"}
+		var args []any
+		statusField := r.model.has(StatusColumn) && r.model.byName[StatusColumn].typ == statusType
+		if statusField {
+			sets = append(sets, StatusColumn+" = ?")
+			args = append(args, coreEntity.Active)
+		}
+		guard, guardArgs := r.versionGuard(v)
+		if guard != "" {
+			sets = append(sets, VersionColumn+" = "+VersionColumn+" + 1")
+		}
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?%s", r.model.table, strings.Join(sets, ", "), r.model.pk.column, guard)
+		id := r.model.fieldValue(v, r.model.pk.column).Interface()
+		if err := r.execVersioned(ctx, v, id, query, append(append(args, id), guardArgs...)...); err != nil {
+			return err
+		}
+		r.bumpVersion(v)
+
+		deletedAt := r.model.fieldValue(v, DeletedAtColumn)
+		deletedAt.Set(reflect.Zero(deletedAt.Type()))
+		if statusField {
+			setStatus(r.model.fieldValue(v, StatusColumn), coreEntity.Active)
+		}
+
+		if hook, ok := any(entity).(AfterRestorer); ok {
+			return hook.AfterRestore(ctx)
+		}
+		return nil
+	})
+}
+
+// execOne runs a statement that must affect a row, returning ErrNotFound otherwise
+func (r *Repository[T]) execOne(ctx context.Context, query string, args ...any) error {
+	result, err := r.app.Database.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// versionGuard returns the condition and argument that pin a statement to the
+// version entity was read at; both are empty when T is not versioned
+func (r *Repository[T]) versionGuard(v reflect.Value) (string, []any) {
+	if !r.model.versioned() {
+		return "", nil
+	}
+	return " AND " + VersionColumn + " = ?", []any{r.model.fieldValue(v, VersionColumn).Interface()}
+}
+
+// execVersioned runs a guarded statement. When nothing matched it looks the row
+// up again: if it still exists with another version the write lost the race
+// and ErrConflict is returned, otherwise ErrNotFound.
+func (r *Repository[T]) execVersioned(ctx context.Context, v reflect.Value, id any, query string, args ...any) error {
+	err := r.execOne(ctx, query, args...)
+	if !errors.Is(err, ErrNotFound) || !r.model.versioned() {
+		return err
+	}
+
+	var count, current int64
+	lookup := fmt.Sprintf("SELECT COUNT(*), COALESCE(MAX(%s), 0) FROM %s WHERE %s = ?",
+		VersionColumn, r.model.table, r.model.pk.column)
+	if err := r.app.Database.QueryRow(ctx, lookup, id).Scan(&count, &current); err != nil {
+		return err
+	}
+	if count > 0 && current != versionOf(r.model.fieldValue(v, VersionColumn)) {
+		return ErrConflict
+	}
+	return ErrNotFound
+}
+
+// bumpVersion increments the version field after a successful guarded write
+func (r *Repository[T]) bumpVersion(v reflect.Value) {
+	if !r.model.versioned() {
+		return
+	}
+	fv := r.model.fieldValue(v, VersionColumn)
+	setInt(fv, versionOf(fv)+1)
+}
+
+// versionOf reads an integer version field
+func versionOf(fv reflect.Value) int64 {
+	if fv.CanInt() {
+		return fv.Int()
+	}
+	return int64(fv.Uint())
+}
+
+// setInt stores a generated id in an integer primary key field
+func setInt(fv reflect.Value, id int64) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fv.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fv.SetUint(uint64(id))
+	}
+}
@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+	utilQuery "github.com/JubaerHossain/rootx/pkg/query"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type post struct {
+	ID        int64             `db:"id"`
+	Title     string            `db:"title"`
+	CreatedAt time.Time         `db:"created_at"`
+	UpdatedAt time.Time         `db:"updated_at"`
+	DeletedAt *time.Time        `db:"deleted_at"`
+	Status    coreEntity.Status `db:"status"`
+	Version   int               `db:"version"`
+}
+
+type note struct {
+	ID   int64  `db:"id"`
+	Body string `db:"body"`
+}
+
+// flakyNote fails its first AfterCreate with a serialization failure, after
+// the key has been assigned
+type flakyNote struct {
+	ID   int64  `db:"id"`
+	Body string `db:"body"`
+}
+
+var flakyNoteCalls int
+
+func (n *flakyNote) TableName() string { return "notes" }
+
+func (n *flakyNote) AfterCreate(ctx context.Context) error {
+	flakyNoteCalls++
+	if flakyNoteCalls == 1 {
+		return &pgconn.PgError{Code: "40001"}
+	}
+	return nil
+}
+
+// fakeDB records statements and answers them from queued results
+type fakeDB struct {
+	dialect database.Dialect
+	queries []string
+	args    [][]any
+	execs   []execResult // popped by Exec; RowsAffected 1 when empty
+	rows    []fakeRow    // popped by QueryRow
+}
+
+type execResult struct {
+	result database.Result
+	err    error
+}
+
+func (db *fakeDB) record(query string, args []any) {
+	db.queries = append(db.queries, query)
+	db.args = append(db.args, args)
+}
+
+func (db *fakeDB) Query(ctx context.Context, query string, args ...any) (database.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, query string, args ...any) database.Row {
+	db.record(query, args)
+	if len(db.rows) == 0 {
+		return fakeRow{err: errors.New("no row queued")}
+	}
+	row := db.rows[0]
+	db.rows = db.rows[1:]
+	return row
+}
+
+func (db *fakeDB) Exec(ctx context.Context, query string, args ...any) (database.Result, error) {
+	db.record(query, args)
+	if len(db.execs) == 0 {
+		return database.Result{RowsAffected: 1}, nil
+	}
+	next := db.execs[0]
+	db.execs = db.execs[1:]
+	return next.result, next.err
+}
+
+func (db *fakeDB) Begin(ctx context.Context) (database.Tx, error) {
+	return db.BeginTx(ctx, database.TxOptions{})
+}
+
+func (db *fakeDB) BeginTx(ctx context.Context, opts database.TxOptions) (database.Tx, error) {
+	return fakeTx{db}, nil
+}
+
+func (db *fakeDB) Dialect() database.Dialect      { return db.dialect }
+func (db *fakeDB) Ping(ctx context.Context) error { return nil }
+func (db *fakeDB) Close()                         {}
+
+type fakeTx struct {
+	*fakeDB
+}
+
+func (tx fakeTx) Commit(ctx context.Context) error   { return nil }
+func (tx fakeTx) Rollback(ctx context.Context) error { return nil }
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, d := range dest {
+		target := reflect.ValueOf(d).Elem()
+		target.Set(reflect.ValueOf(r.values[i]).Convert(target.Type()))
+	}
+	return nil
+}
+
+func newRepo[T any](t *testing.T, db *fakeDB) *Repository[T] {
+	t.Helper()
+	r, err := New[T](&app.App{Database: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRepositoryStatements(t *testing.T) {
+	deletedAt := time.Now()
+	tests := []struct {
+		name      string
+		dialect   database.Dialect
+		run       func(ctx context.Context, db *fakeDB) error
+		wantErr   error
+		wantQuery []string
+	}{
+		{
+			name:    "create returns the key on postgres",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				db.rows = []fakeRow{{values: []any{int64(7)}}}
+				p := &post{Title: "hello"}
+				if err := newRepo[post](t, db).Create(ctx, p); err != nil {
+					return err
+				}
+				if p.ID != 7 || p.Version != 1 || p.CreatedAt.IsZero() || p.UpdatedAt.IsZero() {
+					t.Errorf("created post = %+v", p)
+				}
+				return nil
+			},
+			wantQuery: []string{"INSERT INTO posts (title, created_at, updated_at, deleted_at, status, version) VALUES (?, ?, ?, ?, ?, ?) RETURNING id"},
+		},
+		{
+			name:    "create reads the insert id on mysql",
+			dialect: database.MySQL,
+			run: func(ctx context.Context, db *fakeDB) error {
+				db.execs = []execResult{{result: database.Result{RowsAffected: 1, LastInsertID: 9}}}
+				n := &note{Body: "x"}
+				if err := newRepo[note](t, db).Create(ctx, n); err != nil {
+					return err
+				}
+				if n.ID != 9 {
+					t.Errorf("created note id = %d, want 9", n.ID)
+				}
+				return nil
+			},
+			wantQuery: []string{"INSERT INTO notes (body) VALUES (?)"},
+		},
+		{
+			name:    "update is guarded by the version",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				p := &post{ID: 1, Title: "new", Version: 3}
+				if err := newRepo[post](t, db).Update(ctx, p); err != nil {
+					return err
+				}
+				if p.Version != 4 || p.UpdatedAt.IsZero() {
+					t.Errorf("updated post = %+v", p)
+				}
+				if args := db.args[0]; args[len(args)-2] != int64(1) || args[len(args)-1] != 3 {
+					t.Errorf("update args = %v, want id 1 and version 3 last", args)
+				}
+				return nil
+			},
+			wantQuery: []string{"UPDATE posts SET title = ?, updated_at = ?, deleted_at = ?, status = ?, version = version + 1 WHERE id = ? AND version = ?"},
+		},
+		{
+			name:    "update of a changed row conflicts",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				db.execs = []execResult{{}}
+				db.rows = []fakeRow{{values: []any{int64(1), int64(5)}}}
+				p := &post{ID: 1, Title: "new", Version: 3}
+				err := newRepo[post](t, db).Update(ctx, p)
+				if p.Version != 3 || !p.UpdatedAt.IsZero() {
+					t.Errorf("failed update changed the entity: %+v", p)
+				}
+				return err
+			},
+			wantErr: ErrConflict,
+			wantQuery: []string{
+				"UPDATE posts SET title = ?, updated_at = ?, deleted_at = ?, status = ?, version = version + 1 WHERE id = ? AND version = ?",
+				"SELECT COUNT(*), COALESCE(MAX(version), 0) FROM posts WHERE id = ?",
+			},
+		},
+		{
+			name:    "update of a missing row",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				db.execs = []execResult{{}}
+				db.rows = []fakeRow{{values: []any{int64(0), int64(0)}}}
+				return newRepo[post](t, db).Update(ctx, &post{ID: 1, Version: 3})
+			},
+			wantErr: ErrNotFound,
+			wantQuery: []string{
+				"UPDATE posts SET title = ?, updated_at = ?, deleted_at = ?, status = ?, version = version + 1 WHERE id = ? AND version = ?",
+				"SELECT COUNT(*), COALESCE(MAX(version), 0) FROM posts WHERE id = ?",
+			},
+		},
+		{
+			name:    "delete is soft with a deleted_at column",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				p := &post{ID: 1, Status: coreEntity.Active, Version: 2}
+				if err := newRepo[post](t, db).Delete(ctx, p); err != nil {
+					return err
+				}
+				if p.DeletedAt == nil || p.Status != coreEntity.Deleted || p.Version != 3 {
+					t.Errorf("deleted post = %+v", p)
+				}
+				return nil
+			},
+			wantQuery: []string{"UPDATE posts SET deleted_at = ?, status = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL AND version = ?"},
+		},
+		{
+			name:    "force delete removes the row",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				return newRepo[post](t, db).ForceDelete(ctx, &post{ID: 1, Version: 2})
+			},
+			wantQuery: []string{"DELETE FROM posts WHERE id = ? AND version = ?"},
+		},
+		{
+			name:    "delete without soft delete",
+			dialect: database.MySQL,
+			run: func(ctx context.Context, db *fakeDB) error {
+				return newRepo[note](t, db).Delete(ctx, &note{ID: 1})
+			},
+			wantQuery: []string{"DELETE FROM notes WHERE id = ?"},
+		},
+		{
+			name:    "restore",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				p := &post{ID: 1, DeletedAt: &deletedAt, Status: coreEntity.Deleted, Version: 3}
+				if err := newRepo[post](t, db).Restore(ctx, p); err != nil {
+					return err
+				}
+				if p.DeletedAt != nil || p.Status != coreEntity.Active || p.Version != 4 {
+					t.Errorf("restored post = %+v", p)
+				}
+				return nil
+			},
+			wantQuery: []string{"UPDATE posts SET deleted_at = NULL, status = ?, version = version + 1 WHERE id = ? AND version = ?"},
+		},
+		{
+			name:    "restore of a changed row conflicts",
+			dialect: database.Postgres,
+			run: func(ctx context.Context, db *fakeDB) error {
+				db.execs = []execResult{{}}
+				db.rows = []fakeRow{{values: []any{int64(1), int64(5)}}}
+				p := &post{ID: 1, DeletedAt: &deletedAt, Status: coreEntity.Deleted, Version: 3}
+				err := newRepo[post](t, db).Restore(ctx, p)
+				if p.DeletedAt == nil || p.Status != coreEntity.Deleted || p.Version != 3 {
+					t.Errorf("failed restore changed the entity: %+v", p)
+				}
+				return err
+			},
+			wantErr: ErrConflict,
+			wantQuery: []string{
+				"UPDATE posts SET deleted_at = NULL, status = ?, version = version + 1 WHERE id = ? AND version = ?",
+				"SELECT COUNT(*), COALESCE(MAX(version), 0) FROM posts WHERE id = ?",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeDB{dialect: tt.dialect}
+			err := tt.run(context.Background(), db)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(db.queries, tt.wantQuery) {
+				t.Errorf("queries = %q, want %q", db.queries, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestRepositoryQuery(t *testing.T) {
+	db := &fakeDB{dialect: database.Postgres}
+	r := newRepo[post](t, db)
+	active := func(qb *utilQuery.Builder) { qb.Where(StatusColumn, "=", coreEntity.Active) }
+
+	tests := []struct {
+		name string
+		repo *Repository[post]
+		want string
+	}{
+		{
+			name: "soft deleted rows hidden",
+			repo: r,
+			want: "SELECT id, title, created_at, updated_at, deleted_at, status, version FROM posts WHERE deleted_at IS NULL",
+		},
+		{
+			name: "with trashed",
+			repo: r.WithTrashed(),
+			want: "SELECT id, title, created_at, updated_at, deleted_at, status, version FROM posts",
+		},
+		{
+			name: "only trashed",
+			repo: r.OnlyTrashed(),
+			want: "SELECT id, title, created_at, updated_at, deleted_at, status, version FROM posts WHERE deleted_at IS NOT NULL",
+		},
+		{
+			name: "scoped",
+			repo: r.Scoped(active),
+			want: "SELECT id, title, created_at, updated_at, deleted_at, status, version FROM posts WHERE deleted_at IS NULL AND status = $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _, err := tt.repo.Query().Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query != tt.want {
+				t.Errorf("Query() = %q, want %q", query, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryRetryStartsFromCallerValues(t *testing.T) {
+	db := &fakeDB{dialect: database.Postgres}
+	// The first attempt loses a serialization conflict and is retried
+	db.execs = []execResult{{err: &pgconn.PgError{Code: "40001"}}, {result: database.Result{RowsAffected: 1}}}
+
+	p := &post{ID: 1, Title: "new", Version: 3}
+	if err := newRepo[post](t, db).Update(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.args) != 2 {
+		t.Fatalf("got %d statements, want 2", len(db.args))
+	}
+	for i, args := range db.args {
+		if version := args[len(args)-1]; version != 3 {
+			t.Errorf("attempt %d guarded on version %v, want 3", i+1, version)
+		}
+	}
+	if p.Version != 4 {
+		t.Errorf("version = %d, want 4", p.Version)
+	}
+
+	// A retried create inserts again instead of reusing the key the failed attempt got
+	db = &fakeDB{dialect: database.MySQL, execs: []execResult{
+		{result: database.Result{RowsAffected: 1, LastInsertID: 7}},
+		{result: database.Result{RowsAffected: 1, LastInsertID: 8}},
+	}}
+	n := &flakyNote{Body: "x"}
+	if err := newRepo[flakyNote](t, db).Create(context.Background(), n); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"INSERT INTO notes (body) VALUES (?)", "INSERT INTO notes (body) VALUES (?)"}
+	if !reflect.DeepEqual(db.queries, want) {
+		t.Errorf("queries = %q, want %q", db.queries, want)
+	}
+	if n.ID != 8 {
+		t.Errorf("id = %d, want 8", n.ID)
+	}
+}
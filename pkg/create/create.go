@@ -3,24 +3,29 @@ package create
 import (
 	"bufio"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	"github.com/JubaerHossain/rootx/pkg/core/migration"
 	"github.com/gertd/go-pluralize"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 const (
@@ -38,11 +43,135 @@ const (
 
 var AppName string
 
+var (
+	seedOnly    []string
+	seedForce   bool
+	lintDialect string
+	diffName    string
+	diffDrop    bool
+	squashYes   bool
+	baselineTo  string
+	dumpOutput  string
+	showEnv     string
+	vaultPath   string
+	secretValue string
+)
+
 var Create = &cobra.Command{
 	Use:  "create",
 	Args: cobra.MinimumNArgs(2),
 	RunE: Run,
 }
+
+var Seed = &cobra.Command{
+	Use:   "seed",
+	Short: "Run pending seeders for the current APP_ENV",
+	RunE:  RunSeeders,
+}
+
+var Migrate = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply migrations",
+	RunE:  ApplyMigrations,
+}
+
+var MigrateLint = &cobra.Command{
+	Use:   "lint",
+	Short: "Check migrations for operations that are unsafe on large tables",
+	RunE:  LintMigrations,
+}
+
+var MigrateDiff = &cobra.Command{
+	Use:   "diff",
+	Short: "Generate a migration from the difference between entity structs and the database",
+	RunE:  DiffMigration,
+}
+
+var MigrateSquash = &cobra.Command{
+	Use:   "squash",
+	Short: "Replace all applied migrations with a single baseline of the current schema",
+	RunE:  SquashMigrations,
+}
+
+var MigrateBaseline = &cobra.Command{
+	Use:   "baseline",
+	Short: "Record migrations as applied without running them, for databases created before rootx tracked history",
+	RunE:  BaselineMigrations,
+}
+
+var Schema = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect the database schema",
+}
+
+var SchemaDump = &cobra.Command{
+	Use:   "dump",
+	Short: "Write the current database schema to a canonical SQL file",
+	RunE:  DumpSchema,
+}
+
+var Config = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the application configuration",
+}
+
+var ConfigShow = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration with secrets redacted",
+	RunE:  ShowConfig,
+}
+
+var Secrets = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted secrets vault (passphrase in SECRETS_VAULT_KEY)",
+}
+
+var SecretsSet = &cobra.Command{
+	Use:   "set KEY",
+	Short: "Store a secret in the vault, read from a hidden prompt or stdin",
+	Args:  cobra.ExactArgs(1),
+	RunE:  SetSecret,
+}
+
+var SecretsDelete = &cobra.Command{
+	Use:   "delete KEY",
+	Short: "Remove a secret from the vault",
+	Args:  cobra.ExactArgs(1),
+	RunE:  DeleteSecret,
+}
+
+var SecretsList = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys stored in the vault",
+	RunE:  ListSecrets,
+}
+
+func init() {
+	defaultVault := os.Getenv("SECRETS_VAULT")
+	if defaultVault == "" {
+		defaultVault = "secrets.vault"
+	}
+	Secrets.PersistentFlags().StringVar(&vaultPath, "vault", defaultVault, "vault file (defaults to SECRETS_VAULT)")
+	SecretsSet.Flags().StringVar(&secretValue, "value", "", "take the value from the command line (visible in shell history and ps)")
+	Secrets.AddCommand(SecretsSet, SecretsDelete, SecretsList)
+
+	ConfigShow.Flags().StringVar(&showEnv, "env", "", "environment to load, e.g. production (defaults to APP_ENV)")
+	Config.AddCommand(ConfigShow)
+	SchemaDump.Flags().StringVar(&dumpOutput, "output", "schema.sql", "file to write the schema to")
+	Schema.AddCommand(SchemaDump)
+	MigrateSquash.Flags().BoolVar(&squashYes, "yes", false, "squash without asking for confirmation")
+	Migrate.AddCommand(MigrateSquash)
+	MigrateBaseline.Flags().StringVar(&baselineTo, "to", "", "last migration to record (default: all of them)")
+	Migrate.AddCommand(MigrateBaseline)
+	MigrateDiff.Flags().StringVar(&diffName, "name", "schema_diff", "name of the generated migration")
+	MigrateDiff.Flags().BoolVar(&diffDrop, "drop", false, "drop columns that no entity field maps to (data loss)")
+	Migrate.AddCommand(MigrateDiff)
+	MigrateLint.Flags().StringVar(&lintDialect, "dialect", "", "postgres or mysql (defaults to DB_TYPE)")
+	Migrate.AddCommand(MigrateLint)
+
+	Seed.Flags().StringSliceVar(&seedOnly, "only", nil, "run only the named seeders, e.g. --only users")
+	Seed.Flags().BoolVar(&seedForce, "force", false, "allow running seeders in production")
+}
 func hexToRGB(hex string) (int, int, int) {
 	var r, g, b int
 	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
@@ -365,23 +494,84 @@ func MigrationCreate(cmd *cobra.Command, args []string) error {
 }
 
 func createMigrationFile(name string) error {
-	if _, err := os.Stat("migrations"); os.IsNotExist(err) {
-		os.Mkdir("migrations", 0755)
-	}
-	timestamp := time.Now().Format("2006_01_02_150405")
-	filename := filepath.Join("migrations", fmt.Sprintf("%s_%s.sql", timestamp, name))
 	content := fmt.Sprintf("-- Migration %s\n\n", name) +
 		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n", name) +
 		"    id SERIAL PRIMARY KEY,\n" +
 		"    name VARCHAR(100) NOT NULL,\n" +
+		"    status BOOLEAN NOT NULL DEFAULT TRUE,\n" +
 		"    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
-		"    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\n" +
+		"    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+		"    deleted_at TIMESTAMP NULL,\n" +
+		"    version INTEGER NOT NULL DEFAULT 1\n" +
 		");\n\n" +
 		fmt.Sprintf("CREATE INDEX ON %s (name);\n", name) // Modify column_name with the actual column name
 
+	_, err := writeMigrationFile(name, content)
+	return err
+}
+
+// writeMigrationFile stores content as a new timestamped file in migrations/ and returns its path
+func writeMigrationFile(name, content string) (string, error) {
+	if _, err := os.Stat("migrations"); os.IsNotExist(err) {
+		os.Mkdir("migrations", 0755)
+	}
+	timestamp := time.Now().Format("2006_01_02_150405")
+	filename := filepath.Join("migrations", fmt.Sprintf("%s_%s.sql", timestamp, name))
 	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to create migration file: %w", err)
+		return "", fmt.Errorf("failed to create migration file: %w", err)
+	}
+	return filename, nil
+}
+
+func DiffMigration(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conn, closeConn, err := openConn(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeConn()
+
+	desired, err := migration.LoadEntities(AppRoot, conn.Dialect())
+	if err != nil {
+		return err
+	}
+	current, err := migration.LoadSchema(context.Background(), conn)
+	if err != nil {
+		return err
+	}
+
+	statements := migration.Diff(desired, current, diffDrop)
+	changes := 0
+	for _, statement := range statements {
+		if strings.HasPrefix(statement, migration.DropPrefix) {
+			// Commented out statement: the first line explains it
+			warning := "Warning: " + strings.TrimPrefix(strings.SplitN(statement, "\n", 2)[0], migration.DropPrefix)
+			if strings.Contains(statement, "DROP COLUMN") {
+				warning += " (rerun with --drop)"
+			}
+			fmt.Println(colorize(warning, "#FFA500"))
+			continue
+		}
+		changes++
 	}
+	if changes == 0 {
+		fmt.Println(colorize("Schema is up to date", "#00FF00"))
+		return nil
+	}
+
+	name := Lower(diffName)
+	content := fmt.Sprintf("-- Migration %s\n-- Generated by rootx migrate diff, review before applying\n\n", name) +
+		strings.Join(statements, "\n\n") + "\n"
+	filename, err := writeMigrationFile(name, content)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(colorize("Created "+filename, "#00FF00"))
 	return nil
 }
 
@@ -412,7 +602,8 @@ func createSeedFile(tableName string) error {
 	}
 	timestamp := time.Now().Format("2006_01_02_150405")
 	filename := filepath.Join("seeds", fmt.Sprintf("%s_%s_seeder.sql", timestamp, tableName))
-	content := fmt.Sprintf("-- Seeder for table %s\n\n", tableName) +
+	content := fmt.Sprintf("-- Seeder for table %s\n", tableName) +
+		"-- env: development, test\n\n" +
 		fmt.Sprintf("INSERT INTO %s (name, created_at, updated_at) VALUES\n", tableName) +
 		"    ('Value1', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),\n" +
 		"    ('Value2', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);\n"
@@ -448,42 +639,38 @@ func MigrationWithSeederCreate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func loadEnv() error {
-	err := godotenv.Load(".env")
-	if err != nil {
-		return fmt.Errorf("failed to load .env file: %w", err)
-	}
-	return nil
-}
-
-func connectDB() (*pgxpool.Pool, error) {
-	if err := loadEnv(); err != nil {
-		return nil, fmt.Errorf("failed to load .env file: %w", err)
-	}
-	dbHost := os.Getenv("DB_HOST")
-	dbPortStr := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	dbPort, err := strconv.Atoi(dbPortStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to convert DB_PORT to int: %w", err)
-	}
-
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbPort, dbName)
-
-	config, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+// openConn connects to the database described by cfg and returns a runner connection with its closer
+func openConn(cfg *config.Config) (migration.Conn, func(), error) {
+	switch cfg.DBType {
+	case migration.MySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
+			cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open database connection: %w", err)
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("failed to ping database: %w", err)
+		}
+		return migration.NewSQLConn(db, migration.MySQL), func() { db.Close() }, nil
+	default:
+		poolConfig, err := pgxpool.ParseConfig(database.PostgresDSN(cfg))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse database URL: %w", err)
+		}
+		// The CLI runs one statement at a time
+		poolConfig.MaxConns = 4
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open database connection: %w", err)
+		}
+		if err := pool.Ping(context.Background()); err != nil {
+			pool.Close()
+			return nil, nil, fmt.Errorf("failed to ping database: %w", err)
+		}
+		return migration.NewPgxConn(pool), pool.Close, nil
 	}
-
-	config.MaxConnIdleTime = 10 * time.Minute
-	config.MaxConnLifetime = 60 * time.Minute // Set to 1 hour
-	config.MaxConns = 50000                   // Adjust based on your environment
-	config.MinConns = 100
-
-	return pgxpool.NewWithConfig(context.Background(), config)
 }
 
 func getUserInput(prompt string) string {
@@ -502,14 +689,38 @@ func getUserInput(prompt string) string {
 
 func ApplyMigrations(cmd *cobra.Command, args []string) error {
 
-	pool, err := connectDB()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conn, closeConn, err := openConn(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database")
 	}
-	defer pool.Close()
+	defer closeConn()
 
+	ctx := context.Background()
 	migrationsDir := "migrations"
-	if err := executeScriptsInDirectory(pool, migrationsDir); err != nil {
+	pending, err := migration.PendingMigrations(ctx, conn, migrationsDir)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	var findings []migration.Finding
+	for _, m := range pending {
+		fileFindings, err := migration.LintSQL(m.Path, m.SQL, conn.Dialect())
+		if err != nil {
+			return fmt.Errorf("failed to lint migrations: %w", err)
+		}
+		findings = append(findings, fileFindings...)
+	}
+	if err := reportFindings(findings); err != nil {
+		return err
+	}
+
+	result, err := migration.RunMigrations(ctx, conn, migrationsDir)
+	if err != nil {
 		return fmt.Errorf("failed to execute migration scripts %w", err)
 	}
 
@@ -521,9 +732,300 @@ func ApplyMigrations(cmd *cobra.Command, args []string) error {
 		time.Sleep(100 * time.Millisecond) // Simulate some work being done
 	}
 
+	for _, name := range result.Applied {
+		fmt.Println(colorize("Migrated: "+name, "#00FF00"))
+	}
+	for _, name := range result.Recorded {
+		fmt.Println(colorize("Recorded baseline: "+name, "#00FF00"))
+	}
+	if len(result.Applied)+len(result.Recorded) == 0 {
+		fmt.Println(colorize("Nothing to migrate", "#FFA500"))
+	}
+
+	return nil
+}
+
+func LintMigrations(cmd *cobra.Command, args []string) error {
+	dialect := lintDialect
+	if dialect == "" {
+		dialect = migrationDialect()
+	}
+
+	findings, err := migration.LintDir("migrations", dialect)
+	if err != nil {
+		return fmt.Errorf("failed to lint migrations: %w", err)
+	}
+	if err := reportFindings(findings); err != nil {
+		return err
+	}
+	fmt.Println(colorize("Migrations look safe", "#00FF00")) // Green color for success message
+	return nil
+}
+
+// reportFindings prints every finding and fails if any of them is an error
+func reportFindings(findings []migration.Finding) error {
+	for _, finding := range findings {
+		color := "#FFA500" // Orange for warnings
+		if finding.Severity == migration.SeverityError {
+			color = "#FF0000"
+		}
+		fmt.Println(colorize(finding.String(), color))
+	}
+
+	if migration.HasErrors(findings) {
+		return errors.New("unsafe migrations found, fix them or add a -- rootx:allow <rule> comment")
+	}
+	return nil
+}
+
+func SquashMigrations(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conn, closeConn, err := openConn(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeConn()
+
+	ctx := context.Background()
+	migrationsDir := "migrations"
+	migrations, err := migration.LoadMigrations(migrationsDir)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	if len(migrations) < 2 {
+		fmt.Println(colorize("Nothing to squash", "#FFA500"))
+		return nil
+	}
+
+	pending, err := migration.PendingMigrations(ctx, conn, migrationsDir)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations are not applied yet, apply them before squashing", len(pending))
+	}
+
+	// The dump only covers tables, columns, keys and indexes; refuse rather
+	// than replace migrations that create anything else
+	unsupported, err := migration.UnsupportedObjects(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if len(unsupported) > 0 {
+		for _, object := range unsupported {
+			fmt.Println(colorize("  "+object, "#FFA500"))
+		}
+		return fmt.Errorf("the schema has %d objects a baseline cannot reproduce; "+
+			"write one by hand with pg_dump --schema-only or mysqldump --no-data instead", len(unsupported))
+	}
+
+	schema, err := migration.LoadSchema(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	archiveDir := filepath.Join(migrationsDir, "squashed_"+time.Now().Format("20060102150405"))
+	if !squashYes {
+		answer := getUserInput(fmt.Sprintf("Replace %d migrations with a baseline and move them to %s? [y/N]: ", len(migrations), archiveDir))
+		if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			fmt.Println(colorize("Squash cancelled", "#FFA500"))
+			return nil
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("-- Migration baseline\n" + migration.BaselineDirective + "\n")
+	b.WriteString(fmt.Sprintf("-- Squashed from %d migrations by rootx migrate squash\n", len(migrations)))
+	for _, m := range migrations {
+		b.WriteString(migration.ReplacesDirective + " " + m.Name + "\n")
+	}
+	b.WriteString("\n" + migration.DumpSQL(schema))
+	filename, err := writeMigrationFile("baseline", b.String())
+	if err != nil {
+		return err
+	}
+	if err := migration.MarkApplied(ctx, conn, strings.TrimSuffix(filepath.Base(filename), ".sql")); err != nil {
+		return err
+	}
+
+	// Keep the old files next to the baseline; the runner skips subdirectories
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", archiveDir, err)
+	}
+	for _, m := range migrations {
+		if err := os.Rename(m.Path, filepath.Join(archiveDir, filepath.Base(m.Path))); err != nil {
+			return fmt.Errorf("failed to move %s: %w", m.Path, err)
+		}
+	}
+
+	fmt.Println(colorize(fmt.Sprintf("Squashed %d migrations into %s", len(migrations), filename), "#00FF00"))
+	fmt.Println(colorize("Old migrations moved to "+archiveDir+"; delete them once every database has the baseline", "#FFA500"))
+	return nil
+}
+
+// BaselineMigrations records migrations as applied on a database whose schema
+// already matches them, e.g. one migrated before rootx_migrations existed
+func BaselineMigrations(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conn, closeConn, err := openConn(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeConn()
+
+	recorded, err := migration.Baseline(context.Background(), conn, "migrations", baselineTo)
+	for _, name := range recorded {
+		fmt.Println(colorize("Recorded: "+name, "#00FF00"))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migrations: %w", err)
+	}
+	if len(recorded) == 0 {
+		fmt.Println(colorize("Nothing to record", "#FFA500"))
+	}
+	return nil
+}
+
+func DumpSchema(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	conn, closeConn, err := openConn(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer closeConn()
+
+	ctx := context.Background()
+	schema, err := migration.LoadSchema(ctx, conn)
+	if err != nil {
+		return err
+	}
+	unsupported, err := migration.UnsupportedObjects(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, object := range unsupported {
+		fmt.Println(colorize("Not included in the dump: "+object, "#FFA500"))
+	}
+
+	content := fmt.Sprintf("-- Schema dump (%s), generated by rootx schema dump\n\n", conn.Dialect()) + migration.DumpSQL(schema)
+	if err := os.WriteFile(dumpOutput, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write schema file: %w", err)
+	}
+
+	fmt.Println(colorize("Schema written to "+dumpOutput, "#00FF00"))
+	return nil
+}
+
+// ShowConfig loads the configuration the app would see, merging .env, the
+// .env.<env> overlay and the environment, and prints it with secrets redacted
+func ShowConfig(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(config.Options{Env: showEnv})
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fmt.Println(colorize("Effective configuration for "+cfg.AppEnv, "#00FFFF"))
+	for _, entry := range config.Entries(cfg) {
+		fmt.Printf("%s=%s\n", colorize(entry.Key, "#FFFF00"), entry.Value)
+	}
+	return nil
+}
+
+// openVault opens the vault selected by --vault with SECRETS_VAULT_KEY
+func openVault() (*config.Vault, error) {
+	return config.OpenVault(vaultPath, os.Getenv("SECRETS_VAULT_KEY"))
+}
+
+// readSecretValue returns --value when given, otherwise prompts without echo
+// on a terminal or reads the value piped to stdin
+func readSecretValue(cmd *cobra.Command, key string) (string, error) {
+	if cmd.Flags().Changed("value") {
+		return secretValue, nil
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Print(colorize("Value for "+key+": ", "#00FFFF"))
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return string(value), nil
+	}
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from stdin: %w", err)
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
+
+// SetSecret stores KEY in the vault with a value from readSecretValue
+func SetSecret(cmd *cobra.Command, args []string) error {
+	value, err := readSecretValue(cmd, args[0])
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return errors.New("secret value is empty")
+	}
+	vault, err := config.OpenOrCreateVault(vaultPath, os.Getenv("SECRETS_VAULT_KEY"))
+	if err != nil {
+		return err
+	}
+	vault.Set(args[0], value)
+	if err := vault.Save(); err != nil {
+		return err
+	}
+	fmt.Println(colorize("Secret "+args[0]+" saved to "+vaultPath, "#00FF00"))
+	return nil
+}
+
+// DeleteSecret removes KEY from the vault
+func DeleteSecret(cmd *cobra.Command, args []string) error {
+	vault, err := openVault()
+	if err != nil {
+		return err
+	}
+	vault.Delete(args[0])
+	if err := vault.Save(); err != nil {
+		return err
+	}
+	fmt.Println(colorize("Secret "+args[0]+" removed from "+vaultPath, "#00FF00"))
+	return nil
+}
+
+// ListSecrets prints the vault's keys, never their values
+func ListSecrets(cmd *cobra.Command, args []string) error {
+	vault, err := openVault()
+	if err != nil {
+		return err
+	}
+	for _, key := range vault.Keys() {
+		fmt.Println(key)
+	}
 	return nil
 }
 
+// migrationDialect returns DB_TYPE from the .env file, defaulting to postgres
+func migrationDialect() string {
+	envMap, err := loadEnvFile(".env")
+	if err != nil || envMap["DB_TYPE"] == "" {
+		return migration.Postgres
+	}
+	return strings.ToLower(envMap["DB_TYPE"])
+}
+
 func createAuthFiles(fs afero.Fs, name string) error {
 	createFile(fs, name, path.Join(AuthTemplateDir, "service.stub"), path.Join(name, ServiceDir, name+".go"))
 	createFile(fs, name, path.Join(AuthTemplateDir, "entity.stub"), path.Join(name, EntityDir, name+".go"))
@@ -594,43 +1096,36 @@ func RunSeeders(cmd *cobra.Command, args []string) error {
 		time.Sleep(100 * time.Millisecond) // Simulate some work being done
 	}
 
-	pool, err := connectDB()
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
-	defer pool.Close()
 
-	seedsDir := "seeds"
-	if err := executeScriptsInDirectory(pool, seedsDir); err != nil {
-		return fmt.Errorf("failed to execute seeder scripts: %w", err)
+	conn, closeConn, err := openConn(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	return nil
-}
+	defer closeConn()
 
-func executeScriptsInDirectory(pool *pgxpool.Pool, directory string) error {
-	entries, err := os.ReadDir(directory)
+	result, err := migration.RunSeeders(context.Background(), conn, migration.SeedOptions{
+		Dir:   "seeds",
+		Env:   cfg.AppEnv,
+		Only:  seedOnly,
+		Force: seedForce,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+		return fmt.Errorf("failed to execute seeder scripts: %w", err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		filePath := filepath.Join(directory, entry.Name())
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
-
-		_, err = pool.Exec(context.Background(), string(content))
-		if err != nil {
-			return fmt.Errorf("failed to execute file %s: %w", filePath, err)
-		}
+	for _, name := range result.Applied {
+		fmt.Println(colorize("Seeded: "+name, "#00FF00"))
+	}
+	if len(result.Applied) == 0 {
+		fmt.Println(colorize("Nothing to seed", "#FFA500"))
 	}
 	return nil
 }
+
 func createDocsFile(name string) error {
 	if _, err := os.Stat(name); os.IsNotExist(err) {
 		if err := os.Mkdir(name, 0755); err != nil {
@@ -661,20 +1156,15 @@ func createServerFile(name string) error {
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"github.com/JubaerHossain/rootx/pkg/core/app"
 	"github.com/JubaerHossain/rootx/pkg/core/health"
 	"github.com/JubaerHossain/rootx/pkg/core/middleware"
 	"github.com/JubaerHossain/rootx/pkg/core/monitor"
 	"github.com/JubaerHossain/rootx/pkg/utils"
-	"go.uber.org/zap"
 )
 
 // @title           Golang Starter API
@@ -693,47 +1183,23 @@ func main() {
 	os.Setenv("TZ", tz)
 
 	// Initialize the application
-	application, err := app.StartApp()
+	application, err := app.StartApp(app.WithAutoMigrate())
 	if err != nil {
 		log.Fatalf("❌ Failed to start application: %v", err)
 	}
-	// Initialize HTTP server
-	httpServer := InitHTTPServer(application)
-
-	// Create error channel for HTTP server
-	httpErrChan := make(chan error, 1)
 
-	go func() {
-		if err := StartHTTPServer(application, httpServer); err != nil {
-			httpErrChan <- err
-		}
-	}()
-
-	// Graceful shutdown
-	gracefulShutdown(httpServer, application, 30*time.Second, httpErrChan)
-}
-
-func InitHTTPServer(application *app.App) *http.Server {
-	routes := SetupRoutes(application)
-	corsRoutes := middleware.CorsMiddleware(routes)
-	application.SetupHTTPServer(corsRoutes)
-	return application.HttpServer
-}
-
-func StartHTTPServer(application *app.App, server *http.Server) error {
-	application.Logger.Info("Starting HTTP server", zap.Int("port", application.Config.AppPort))
-	err := server.ListenAndServe()
-	if err != nil && err != http.ErrServerClosed {
-		return fmt.Errorf("could not start server: %w", err)
+	// Serve until SIGINT/SIGTERM, then drain and close resources
+	if err := application.Run(context.Background(), middleware.CorsMiddleware(SetupRoutes(application))); err != nil {
+		log.Fatalf("❌ Server stopped with error: %v", err)
 	}
-	return nil
 }
 
 func SetupRoutes(application *app.App) http.Handler {
 	mux := http.NewServeMux()
 
-	// Register health check endpoint
-	mux.Handle("/health", middleware.LoggingMiddleware(http.HandlerFunc(health.HealthCheckHandler())))
+	// Register health check endpoints
+	mux.Handle("/health", middleware.LoggingMiddleware(http.HandlerFunc(health.HealthCheckHandler(application))))
+	mux.Handle("/ready", health.ReadinessHandler(application))
 
 	// Register monitoring endpoint
 	mux.Handle("/metrics", monitor.MetricsHandler())
@@ -748,33 +1214,6 @@ func SetupRoutes(application *app.App) http.Handler {
 	))
 
 	return middleware.PrometheusMiddleware(mux, monitor.RequestsTotal(), monitor.RequestDuration())
-}
-
-func gracefulShutdown(httpServer *http.Server, application *app.App, timeout time.Duration, httpErrChan <-chan error) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	select {
-	case <-quit:
-		application.Logger.Info("Shutdown signal received")
-	case err := <-httpErrChan:
-		application.Logger.Error("HTTP server error", zap.Error(err))
-	}
-
-	application.Logger.Info("Shutting down HTTP server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	if err := httpServer.Shutdown(ctx); err != nil {
-		application.Logger.Error("Could not gracefully shutdown the HTTP server", zap.Error(err))
-	}
-
-	if err := application.CloseResources(); err != nil {
-		application.Logger.Error("Error closing resources", zap.Error(err))
-	}
-
-	application.Logger.Info("HTTP server gracefully stopped")
 }`
 
 	// Check if the main.go file already exists, if not create it
@@ -816,6 +1255,7 @@ func createMainFile(templatePath, targetPath string) error {
 func createEnvFile() error {
 	envContent := `VERSION=1.0.0
 APP_ENV=development
+# LOG_LEVEL defaults to debug in development and info in production
 APP_PORT=8084
 DOMAIN=http://localhost:9008
 DB_TYPE=postgres
@@ -829,18 +1269,32 @@ DB_MAX_IDLE_CONNS=50
 DB_MAX_CONN_LIFETIME=10m
 MAX_CONNS=1000
 MIN_CONNS=100
+DB_REPLICAS=
+DB_REPLICA_CHECK_INTERVAL=5
 MIGRATE=true
-SEED=true
+# SEED=true runs pending seeders at every boot; leave it off outside local development
+SEED=false
 REDIS_EXP=3600
 REDIS_URI=redis://localhost:6379
 REDIS_PASSWORD=
 REDIS_DB=0
+# REDIS_MODE single, sentinel (REDIS_URI lists the sentinels) or cluster (seed nodes)
+REDIS_MODE=single
+REDIS_MASTER_NAME=
+REDIS_TLS=false
 IS_REDIS=false
+CACHE_MEMORY_SIZE=10000
+CACHE_L1=false
+CACHE_L1_SIZE=1000
+CACHE_L1_TTL=5s
 RATE_LIMIT_ENABLED=true
 RATE_LIMIT=500
 RATE_LIMIT_DURATION=3m
+CORS_ALLOWED_ORIGINS=*
+CONFIG_WATCH=false
 JWT_SECRET_KEY=mysecretkey
 JWT_EXPIRATION=24h
+CURSOR_SECRET=
 STORAGE_DISK=s3
 STORAGE_PATH=storage
 AWS_REGION=Default Region
@@ -854,7 +1308,9 @@ OTP_RESEND_DURATION=300
 READ_TIMEOUT=30
 WRITE_TIMEOUT=30
 IDLE_TIMEOUT=120
-MAX_HEADER_BYTES=1048576`
+MAX_HEADER_BYTES=1048576
+SHUTDOWN_TIMEOUT=30
+SHUTDOWN_DRAIN_WAIT=0`
 
 	// Open or create the .env file
 	envFile, err := os.Create(".env")
@@ -0,0 +1,276 @@
+package utilQuery
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+)
+
+// ErrUnknownColumn is returned (wrapped) when a column is not a valid
+// identifier or is not in the builder's whitelist
+var ErrUnknownColumn = errors.New("unknown column")
+
+// ErrInvalidQuery is returned (wrapped) for unsupported operators and sort directions
+var ErrInvalidQuery = errors.New("invalid query")
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Operators accepted by Builder.Where
+const (
+	OpEq        = "="
+	OpNotEq     = "!="
+	OpLt        = "<"
+	OpLte       = "<="
+	OpGt        = ">"
+	OpGte       = ">="
+	OpLike      = "like"
+	OpILike     = "ilike"
+	OpIn        = "in"
+	OpNotIn     = "not in"
+	OpIsNull    = "is null"
+	OpIsNotNull = "is not null"
+)
+
+// Builder builds a parameterised SELECT. Column names are checked against the
+// whitelist given to Allow (or, without one, against identifier syntax), values
+// are always bound, and placeholders are rendered for the builder's dialect.
+// The first error is kept and returned by Build.
+type Builder struct {
+	dialect database.Dialect
+	table   string
+	columns []string
+	allowed map[string]bool
+	wheres  []condition
+	args    []any
+	orders  []Sort
+	limit   int
+	offset  int
+	err     error
+}
+
+// condition is a WHERE term. ILIKE is spelled per dialect, so it is kept
+// apart and rendered by Build once the dialect is final.
+type condition struct {
+	sql   string
+	ilike string // column compared case-insensitively
+}
+
+// maxMySQLLimit stands in for "no limit" because MySQL has no OFFSET without LIMIT
+const maxMySQLLimit = "18446744073709551615"
+
+// likeEscaper escapes the LIKE wildcards and the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes %, _ and \ in s so it matches literally inside a LIKE
+// pattern, e.g. "%" + EscapeLike(search) + "%"
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// Select starts a query on table returning columns
+func Select(table string, columns ...string) *Builder {
+	b := &Builder{table: table}
+	if !identifier.MatchString(table) {
+		b.fail(fmt.Errorf("%w: table %q", ErrInvalidQuery, table))
+	}
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// Dialect renders $n placeholders for Postgres and ? for MySQL. Without it the
+// query keeps ? placeholders, which database.DB rebinds itself.
+func (b *Builder) Dialect(dialect database.Dialect) *Builder {
+	b.dialect = dialect
+	return b
+}
+
+// Allow whitelists the columns that may be selected, filtered and sorted on
+func (b *Builder) Allow(columns ...string) *Builder {
+	if b.allowed == nil {
+		b.allowed = make(map[string]bool, len(columns))
+	}
+	for _, column := range columns {
+		b.allowed[column] = true
+	}
+	return b
+}
+
+// Columns replaces the selected columns
+func (b *Builder) Columns(columns ...string) *Builder {
+	b.columns = append([]string(nil), columns...)
+	return b
+}
+
+// SelectedColumns returns the columns the query will return, in order
+func (b *Builder) SelectedColumns() []string {
+	return append([]string(nil), b.columns...)
+}
+
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// column validates a column name, recording an error if it is not allowed
+func (b *Builder) column(name string) bool {
+	if !identifier.MatchString(name) || (b.allowed != nil && !b.allowed[name]) {
+		b.fail(fmt.Errorf("%w: %q", ErrUnknownColumn, name))
+		return false
+	}
+	return true
+}
+
+// Where adds "column op value", ANDed with the other conditions. value is
+// ignored for OpIsNull/OpIsNotNull and must be a slice for OpIn/OpNotIn.
+func (b *Builder) Where(column, op string, value any) *Builder {
+	if !b.column(column) {
+		return b
+	}
+
+	switch op = strings.ToLower(strings.TrimSpace(op)); op {
+	case OpEq, OpNotEq, "<>", OpLt, OpLte, OpGt, OpGte, OpLike:
+		if op == "<>" {
+			op = OpNotEq
+		}
+		b.wheres = append(b.wheres, condition{sql: fmt.Sprintf("%s %s ?", column, strings.ToUpper(op))})
+		b.args = append(b.args, value)
+	case OpILike:
+		b.wheres = append(b.wheres, condition{ilike: column})
+		b.args = append(b.args, value)
+	case OpIn, OpNotIn:
+		values := reflect.ValueOf(value)
+		if values.Kind() != reflect.Slice {
+			b.fail(fmt.Errorf("%w: %s needs a list of values", ErrInvalidQuery, op))
+			return b
+		}
+		if values.Len() == 0 {
+			// IN () is not valid SQL; an empty IN matches nothing and NOT IN everything
+			if op == OpIn {
+				b.wheres = append(b.wheres, condition{sql: "1 = 0"})
+			}
+			return b
+		}
+		marks := make([]string, values.Len())
+		for i := range marks {
+			marks[i] = "?"
+			b.args = append(b.args, values.Index(i).Interface())
+		}
+		b.wheres = append(b.wheres, condition{sql: fmt.Sprintf("%s %s (%s)", column, strings.ToUpper(op), strings.Join(marks, ", "))})
+	case OpIsNull, OpIsNotNull:
+		b.wheres = append(b.wheres, condition{sql: column + " " + strings.ToUpper(op)})
+	default:
+		b.fail(fmt.Errorf("%w: operator %q", ErrInvalidQuery, op))
+	}
+	return b
+}
+
+// WhereRaw adds a trusted SQL condition with ? placeholders. Never build
+// sql from user input; use Where instead.
+func (b *Builder) WhereRaw(sql string, args ...any) *Builder {
+	b.wheres = append(b.wheres, condition{sql: "(" + sql + ")"})
+	b.args = append(b.args, args...)
+	return b
+}
+
+// OrderBy sorts by column. direction is "asc" or "desc" (any case); empty means asc.
+func (b *Builder) OrderBy(column, direction string) *Builder {
+	if !b.column(column) {
+		return b
+	}
+	switch strings.ToLower(strings.TrimSpace(direction)) {
+	case "", "asc":
+		b.orders = append(b.orders, Sort{Column: column})
+	case "desc":
+		b.orders = append(b.orders, Sort{Column: column, Desc: true})
+	default:
+		b.fail(fmt.Errorf("%w: sort direction %q", ErrInvalidQuery, direction))
+	}
+	return b
+}
+
+// Limit sets the maximum number of rows; 0 means no limit
+func (b *Builder) Limit(limit int) *Builder {
+	b.limit = limit
+	return b
+}
+
+// Offset skips the first offset rows
+func (b *Builder) Offset(offset int) *Builder {
+	b.offset = offset
+	return b
+}
+
+// whereClause renders " WHERE ..." with ? placeholders
+func (b *Builder) whereClause() string {
+	if len(b.wheres) == 0 {
+		return ""
+	}
+	terms := make([]string, len(b.wheres))
+	for i, w := range b.wheres {
+		switch {
+		case w.ilike == "":
+			terms[i] = w.sql
+		case b.dialect == database.Postgres:
+			terms[i] = w.ilike + " ILIKE ?"
+		default:
+			terms[i] = fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", w.ilike)
+		}
+	}
+	return " WHERE " + strings.Join(terms, " AND ")
+}
+
+func (b *Builder) render(query string) string {
+	if b.dialect == "" {
+		return query
+	}
+	return b.dialect.Rebind(query)
+}
+
+// Build returns the SELECT statement and its arguments
+func (b *Builder) Build() (string, []any, error) {
+	columns := "*"
+	if len(b.columns) > 0 {
+		for _, column := range b.columns {
+			b.column(column)
+		}
+		columns = strings.Join(b.columns, ", ")
+	}
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s", columns, b.table, b.whereClause())
+	if len(b.orders) > 0 {
+		orders := make([]string, len(b.orders))
+		for i, o := range b.orders {
+			orders[i] = o.Column + " ASC"
+			if o.Desc {
+				orders[i] = o.Column + " DESC"
+			}
+		}
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if b.limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", b.limit)
+	} else if b.offset > 0 && b.dialect == database.MySQL {
+		query += " LIMIT " + maxMySQLLimit
+	}
+	if b.offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", b.offset)
+	}
+	return b.render(query), append([]any(nil), b.args...), nil
+}
+
+// BuildCount returns a COUNT(*) over the same conditions, ignoring order and limits
+func (b *Builder) BuildCount() (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", b.table, b.whereClause())
+	return b.render(query), append([]any(nil), b.args...), nil
+}
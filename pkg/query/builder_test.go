@@ -0,0 +1,142 @@
+package utilQuery
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+)
+
+func TestBuilderBuild(t *testing.T) {
+	tests := []struct {
+		name      string
+		build     func() *Builder
+		wantPg    string
+		wantMySQL string
+		wantArgs  []any
+		wantErr   error
+	}{
+		{
+			name:      "select all",
+			build:     func() *Builder { return Select("users") },
+			wantPg:    "SELECT * FROM users",
+			wantMySQL: "SELECT * FROM users",
+		},
+		{
+			name: "conditions, order and limit",
+			build: func() *Builder {
+				return Select("users", "id", "name").
+					Where("status", "=", true).
+					Where("age", ">=", 18).
+					Where("id", OpIn, []int{1, 2}).
+					Where("deleted_at", OpIsNull, nil).
+					OrderBy("name", "ASC").
+					OrderBy("id", "desc").
+					Limit(10).
+					Offset(20)
+			},
+			wantPg: "SELECT id, name FROM users WHERE status = $1 AND age >= $2 AND id IN ($3, $4) AND deleted_at IS NULL " +
+				"ORDER BY name ASC, id DESC LIMIT 10 OFFSET 20",
+			wantMySQL: "SELECT id, name FROM users WHERE status = ? AND age >= ? AND id IN (?, ?) AND deleted_at IS NULL " +
+				"ORDER BY name ASC, id DESC LIMIT 10 OFFSET 20",
+			wantArgs: []any{true, 18, 1, 2},
+		},
+		{
+			name:      "ilike per dialect",
+			build:     func() *Builder { return Select("users").Where("name", OpILike, "%al%") },
+			wantPg:    "SELECT * FROM users WHERE name ILIKE $1",
+			wantMySQL: "SELECT * FROM users WHERE LOWER(name) LIKE LOWER(?)",
+			wantArgs:  []any{"%al%"},
+		},
+		{
+			name:      "empty in matches nothing",
+			build:     func() *Builder { return Select("users").Where("id", OpIn, []int{}).Where("id", OpNotIn, []int{}) },
+			wantPg:    "SELECT * FROM users WHERE 1 = 0",
+			wantMySQL: "SELECT * FROM users WHERE 1 = 0",
+		},
+		{
+			name:      "offset without limit",
+			build:     func() *Builder { return Select("users").Offset(5) },
+			wantPg:    "SELECT * FROM users OFFSET 5",
+			wantMySQL: "SELECT * FROM users LIMIT 18446744073709551615 OFFSET 5",
+		},
+		{
+			name:      "raw condition",
+			build:     func() *Builder { return Select("users").WhereRaw("a = ? OR b = ?", 1, 2).Where("c", "<>", 3) },
+			wantPg:    "SELECT * FROM users WHERE (a = $1 OR b = $2) AND c != $3",
+			wantMySQL: "SELECT * FROM users WHERE (a = ? OR b = ?) AND c != ?",
+			wantArgs:  []any{1, 2, 3},
+		},
+		{
+			name:    "column not in whitelist",
+			build:   func() *Builder { return Select("users").Allow("id").Where("password", "=", "x") },
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "selected column not in whitelist",
+			build:   func() *Builder { return Select("users", "id", "secret").Allow("id") },
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "injected column",
+			build:   func() *Builder { return Select("users").OrderBy("id; DROP TABLE users", "asc") },
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "unknown operator",
+			build:   func() *Builder { return Select("users").Where("id", "between", 1) },
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "bad sort direction",
+			build:   func() *Builder { return Select("users").OrderBy("id", "sideways") },
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "in without a list",
+			build:   func() *Builder { return Select("users").Where("id", OpIn, 1) },
+			wantErr: ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		for dialect, want := range map[database.Dialect]string{database.Postgres: tt.wantPg, database.MySQL: tt.wantMySQL} {
+			t.Run(tt.name+"/"+string(dialect), func(t *testing.T) {
+				// The dialect is set last: ILIKE must still be spelled for it
+				query, args, err := tt.build().Dialect(dialect).Build()
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Build() error = %v, want %v", err, tt.wantErr)
+				}
+				if query != want {
+					t.Errorf("Build() query = %q, want %q", query, want)
+				}
+				if len(args) > 0 || len(tt.wantArgs) > 0 {
+					if !reflect.DeepEqual(args, tt.wantArgs) {
+						t.Errorf("Build() args = %v, want %v", args, tt.wantArgs)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestBuilderBuildCount(t *testing.T) {
+	query, args, err := Select("users", "id").Dialect(database.Postgres).
+		Where("status", "=", true).OrderBy("id", "desc").Limit(10).Offset(10).BuildCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT COUNT(*) FROM users WHERE status = $1"; query != want {
+		t.Errorf("BuildCount() query = %q, want %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{true}) {
+		t.Errorf("BuildCount() args = %v", args)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got, want := EscapeLike(`50%_off\`), `50\%\_off\\`; got != want {
+		t.Errorf("EscapeLike() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,433 @@
+package utilQuery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
+)
+
+// CountMode controls how CursorPaginate fills in TotalItems
+type CountMode int
+
+const (
+	// CountNone skips counting; TotalItems and TotalPages are left at 0. It is
+	// the default because a COUNT(*) per page defeats keyset pagination.
+	CountNone CountMode = iota
+	// CountExact runs SELECT COUNT(*) with the query's filters
+	CountExact
+	// CountEstimate reads the planner's row estimate for the whole table
+	// (filters are ignored) and sets TotalEstimated
+	CountEstimate
+)
+
+// cursorKeyLabel separates the cursor key derived from JWT_SECRET_KEY from
+// the JWT signing key itself
+const cursorKeyLabel = "rootx-cursor-key"
+
+// CursorOptions configures CursorPaginate
+type CursorOptions struct {
+	// Key is the unique column appended to the sort as a tie-breaker, default "id"
+	Key string
+	// Count selects no (default), exact or estimated total count
+	Count CountMode
+	// DefaultLimit and MaxLimit bound the limit query parameter (defaults 10 and 100)
+	DefaultLimit int
+	MaxLimit     int
+	// Secret signs cursors; defaults to Config.CursorSecret, then a key derived
+	// from Config.JwtSecretKey
+	Secret []byte
+}
+
+// cursor is the decoded form of a next_cursor/prev_cursor token
+type cursor struct {
+	Backward bool     `json:"b,omitempty"`
+	Sort     string   `json:"s"` // the sort the cursor was made for
+	Kinds    string   `json:"k"` // one type letter per value, see encodeValue
+	Values   []string `json:"v"`
+}
+
+// CursorPaginate runs qb with keyset pagination. The cursor query parameter
+// holds an opaque, signed position; an empty or missing cursor starts at the
+// first page. Sort keys come from qb's OrderBy (plus opts.Key as tie-breaker)
+// and must not be NULL.
+//
+// scan reads one row using the columns in order and returns the item along with
+// a column to value (or pointer) map containing at least the sort columns;
+// the ScanDest targets map fits. Sort columns that qb does not select (e.g.
+// left out by fields=) are read for the cursor only: their pointers are reset
+// to the zero value afterwards, so the item matches an offset page.
+func CursorPaginate[T any](req *http.Request, app *app.App, qb *Builder, opts CursorOptions,
+	scan func(rows database.Rows, columns []string) (T, map[string]any, error)) ([]T, coreEntity.Pagination, error) {
+	ctx := req.Context()
+	if len(qb.columns) == 0 {
+		return nil, coreEntity.Pagination{}, errors.New("cursor pagination needs the selected columns listed")
+	}
+	if opts.Key == "" {
+		opts.Key = "id"
+	}
+	if opts.DefaultLimit <= 0 {
+		opts.DefaultLimit = 10
+	}
+	if opts.MaxLimit <= 0 {
+		opts.MaxLimit = 100
+	}
+	secret := opts.Secret
+	if len(secret) == 0 {
+		secret = cursorSecret(app.Config.CursorSecret, app.Config.JwtSecretKey)
+	}
+	if len(secret) == 0 {
+		return nil, coreEntity.Pagination{}, errors.New("cursor secret is not configured")
+	}
+
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = opts.DefaultLimit
+	}
+	limit = min(limit, opts.MaxLimit)
+
+	sorts := append([]Sort(nil), qb.orders...)
+	if !slices.ContainsFunc(sorts, func(s Sort) bool { return s.Column == opts.Key }) {
+		sorts = append(sorts, Sort{Column: opts.Key})
+	}
+	signature := sortSignature(sorts)
+
+	// The count uses the filters only, so take it before the keyset condition is added
+	pagination := coreEntity.Pagination{}
+	switch opts.Count {
+	case CountExact:
+		countQuery, args, err := qb.BuildCount()
+		if err != nil {
+			return nil, pagination, err
+		}
+		if err := app.Database.QueryRow(ctx, countQuery, args...).Scan(&pagination.TotalItems); err != nil {
+			return nil, pagination, err
+		}
+	case CountEstimate:
+		if err := estimateCount(req, app, qb.table, &pagination.TotalItems); err != nil {
+			return nil, pagination, err
+		}
+		pagination.TotalEstimated = true
+	}
+	if pagination.TotalItems > 0 {
+		pagination.TotalPages = (pagination.TotalItems + limit - 1) / limit
+	}
+
+	var current *cursor
+	if token := req.URL.Query().Get("cursor"); token != "" {
+		c, err := decodeCursor(token, secret)
+		if err != nil {
+			return nil, pagination, err
+		}
+		if c.Sort != signature || len(c.Values) != len(sorts) {
+			return nil, pagination, fmt.Errorf("%w: cursor does not match the sort order", ErrInvalidQuery)
+		}
+		current = c
+	}
+
+	backward := current != nil && current.Backward
+	query := *qb
+	query.orders = sorts
+	if backward {
+		// Walk back from the cursor in reverse order, then flip the rows afterwards
+		query.orders = make([]Sort, len(sorts))
+		for i, s := range sorts {
+			query.orders[i] = Sort{Column: s.Column, Desc: !s.Desc}
+		}
+	}
+	query.wheres = slices.Clone(qb.wheres)
+	query.args = slices.Clone(qb.args)
+	// Sort keys are needed for the cursors even when fields= left them out
+	query.columns = slices.Clone(qb.columns)
+	var internal []string
+	for _, s := range sorts {
+		if !slices.Contains(query.columns, s.Column) {
+			query.columns = append(query.columns, s.Column)
+			internal = append(internal, s.Column)
+		}
+	}
+	if current != nil {
+		values, err := current.decodeValues()
+		if err != nil {
+			return nil, pagination, err
+		}
+		condition, args := keysetCondition(query.orders, values)
+		query.WhereRaw(condition, args...)
+	}
+	query.Limit(limit + 1).Offset(0)
+
+	sql, args, err := query.Build()
+	if err != nil {
+		return nil, pagination, err
+	}
+	rows, err := app.Database.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, pagination, err
+	}
+	defer rows.Close()
+
+	var items []T
+	var keys [][]any
+	for rows.Next() {
+		item, row, err := scan(rows, query.columns)
+		if err != nil {
+			return nil, pagination, err
+		}
+		key := make([]any, len(sorts))
+		for i, s := range sorts {
+			value, ok := row[s.Column]
+			if !ok {
+				return nil, pagination, fmt.Errorf("scan did not return sort column %s", s.Column)
+			}
+			key[i] = deref(value)
+		}
+		for _, column := range internal {
+			resetTarget(row[column])
+		}
+		items = append(items, item)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, pagination, err
+	}
+
+	more := len(items) > limit
+	if more {
+		items, keys = items[:limit], keys[:limit]
+	}
+	if backward {
+		slices.Reverse(items)
+		slices.Reverse(keys)
+	}
+
+	// Going forward there is a previous page whenever we started from a cursor;
+	// going backward there is always a next page (the one we came from)
+	hasNext, hasPrev := more, current != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if len(items) > 0 {
+		if hasNext {
+			if pagination.NextCursor, err = encodeCursor(false, signature, keys[len(keys)-1], secret); err != nil {
+				return nil, pagination, err
+			}
+		}
+		if hasPrev {
+			if pagination.PrevCursor, err = encodeCursor(true, signature, keys[0], secret); err != nil {
+				return nil, pagination, err
+			}
+		}
+	}
+	pagination.TotalCurrentItems = len(items)
+	return items, pagination, nil
+}
+
+// keysetCondition renders the "after this key" condition for orders, e.g. for
+// (a DESC, id ASC): a < ? OR (a = ? AND id > ?)
+func keysetCondition(orders []Sort, values []any) (string, []any) {
+	var terms []string
+	var args []any
+	for i, s := range orders {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, orders[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if s.Desc {
+			op = " < ?"
+		}
+		parts = append(parts, s.Column+op)
+		args = append(args, values[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return strings.Join(terms, " OR "), args
+}
+
+// estimateCount reads the table's row estimate from the catalog
+func estimateCount(req *http.Request, app *app.App, table string, total *int) error {
+	var estimate int64
+	var err error
+	if app.Database.Dialect() == database.MySQL {
+		err = app.Database.QueryRow(req.Context(),
+			"SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?",
+			table).Scan(&estimate)
+	} else {
+		err = app.Database.QueryRow(req.Context(),
+			"SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = to_regclass(?)",
+			table).Scan(&estimate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to estimate row count: %w", err)
+	}
+	*total = int(estimate)
+	return nil
+}
+
+func sortSignature(sorts []Sort) string {
+	parts := make([]string, len(sorts))
+	for i, s := range sorts {
+		parts[i] = s.Column
+		if s.Desc {
+			parts[i] = "-" + s.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// resetTarget sets the value behind a scan target pointer to its zero value
+func resetTarget(target any) {
+	v := reflect.ValueOf(target)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v.Elem().SetZero()
+	}
+}
+
+// deref follows pointers so ScanDest targets can be used as key values
+func deref(value any) any {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func encodeCursor(backward bool, signature string, key []any, secret []byte) (string, error) {
+	c := cursor{Backward: backward, Sort: signature}
+	var kinds strings.Builder
+	for _, value := range key {
+		kind, text, err := encodeValue(value)
+		if err != nil {
+			return "", err
+		}
+		kinds.WriteByte(kind)
+		c.Values = append(c.Values, text)
+	}
+	c.Kinds = kinds.String()
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(cursorMAC(body, secret)), nil
+}
+
+func decodeCursor(token string, secret []byte) (*cursor, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, cursorMAC(body, secret)) {
+		return nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil || len(c.Kinds) != len(c.Values) {
+		return nil, invalid
+	}
+	return &c, nil
+}
+
+// cursorSecret returns CURSOR_SECRET, or else a key derived from the JWT key
+// so that a leaked cursor key cannot be used to sign tokens
+func cursorSecret(cursorKey, jwtKey string) []byte {
+	if cursorKey != "" {
+		return []byte(cursorKey)
+	}
+	if jwtKey == "" {
+		return nil
+	}
+	mac := hmac.New(sha256.New, []byte(jwtKey))
+	mac.Write([]byte(cursorKeyLabel))
+	return mac.Sum(nil)
+}
+
+func cursorMAC(body string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("rootx-cursor:" + body))
+	return mac.Sum(nil)[:16]
+}
+
+// encodeValue turns a sort key value into a type letter and its text form:
+// i int, u uint, f float64, g float32, b bool, t time, s string. Floats keep
+// their own precision so a REAL key decodes to the exact stored value.
+func encodeValue(value any) (byte, string, error) {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64:
+		return 'i', fmt.Sprint(v), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return 'u', fmt.Sprint(v), nil
+	case float32:
+		return 'g', strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return 'f', strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return 'b', strconv.FormatBool(v), nil
+	case time.Time:
+		return 't', v.Format(time.RFC3339Nano), nil
+	case string:
+		return 's', v, nil
+	case []byte:
+		return 's', string(v), nil
+	case nil:
+		return 0, "", errors.New("cursor sort keys must not be NULL")
+	}
+	return 0, "", fmt.Errorf("unsupported cursor sort key type %T", value)
+}
+
+func (c *cursor) decodeValues() ([]any, error) {
+	values := make([]any, len(c.Values))
+	for i, text := range c.Values {
+		var value any
+		var err error
+		switch c.Kinds[i] {
+		case 'i':
+			value, err = strconv.ParseInt(text, 10, 64)
+		case 'u':
+			value, err = strconv.ParseUint(text, 10, 64)
+		case 'f':
+			value, err = strconv.ParseFloat(text, 64)
+		case 'g':
+			var f float64
+			f, err = strconv.ParseFloat(text, 32)
+			value = float32(f)
+		case 'b':
+			value, err = strconv.ParseBool(text)
+		case 't':
+			value, err = time.Parse(time.RFC3339Nano, text)
+		case 's':
+			value = text
+		default:
+			err = errors.New("unknown kind")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
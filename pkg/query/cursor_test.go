@@ -0,0 +1,212 @@
+package utilQuery
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JubaerHossain/rootx/pkg/core/app"
+	"github.com/JubaerHossain/rootx/pkg/core/config"
+	"github.com/JubaerHossain/rootx/pkg/core/database"
+)
+
+// fakeDB answers every query with rows, which hold one value per selected column
+type fakeDB struct {
+	rows    [][]any
+	queries []string
+}
+
+func (db *fakeDB) Query(ctx context.Context, query string, args ...any) (database.Rows, error) {
+	db.queries = append(db.queries, query)
+	return &fakeRows{rows: db.rows}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, query string, args ...any) database.Row {
+	db.queries = append(db.queries, query)
+	return &fakeRows{rows: [][]any{{len(db.rows)}}, pos: 1}
+}
+
+func (db *fakeDB) Exec(ctx context.Context, query string, args ...any) (database.Result, error) {
+	return database.Result{}, errors.New("not implemented")
+}
+
+func (db *fakeDB) Begin(ctx context.Context) (database.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (db *fakeDB) BeginTx(ctx context.Context, opts database.TxOptions) (database.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (db *fakeDB) Dialect() database.Dialect      { return database.Postgres }
+func (db *fakeDB) Ping(ctx context.Context) error { return nil }
+func (db *fakeDB) Close()                         {}
+
+type fakeRows struct {
+	rows [][]any
+	pos  int
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.rows[r.pos-1][i]))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+type item struct {
+	ID   int64
+	Name string
+}
+
+func scanItem(rows database.Rows, columns []string) (*item, map[string]any, error) {
+	var it item
+	targets := map[string]any{"id": &it.ID, "name": &it.Name}
+	if err := rows.Scan(ScanDest(columns, targets)...); err != nil {
+		return nil, nil, err
+	}
+	return &it, targets, nil
+}
+
+func TestCursorPaginateStripsInternalColumns(t *testing.T) {
+	db := &fakeDB{rows: [][]any{{"a", int64(1)}, {"b", int64(2)}, {"c", int64(3)}}}
+	application := &app.App{Config: &config.Config{CursorSecret: "secret"}, Database: db}
+	req := httptest.NewRequest("GET", "/items?cursor=&limit=2", nil)
+
+	// fields=name: id is only selected for the cursor
+	qb := Select("items", "id", "name").Dialect(database.Postgres).Columns("name")
+	items, pagination, err := CursorPaginate(req, application, qb, CursorOptions{Count: CountNone}, scanItem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*item{{Name: "a"}, {Name: "b"}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %+v, want %+v", items, want)
+	}
+	if pagination.NextCursor == "" || pagination.PrevCursor != "" {
+		t.Errorf("cursors = %q/%q, want only next", pagination.NextCursor, pagination.PrevCursor)
+	}
+	if want := "SELECT name, id FROM items ORDER BY id ASC LIMIT 3"; !slices.Equal(db.queries, []string{want}) {
+		t.Errorf("queries = %q, want %q", db.queries, want)
+	}
+
+	// The next page starts after the last key even though id was not returned
+	db.queries = nil
+	req = httptest.NewRequest("GET", "/items?limit=2&cursor="+pagination.NextCursor, nil)
+	if _, _, err := CursorPaginate(req, application, qb, CursorOptions{Count: CountNone}, scanItem); err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT name, id FROM items WHERE ((id > $1)) ORDER BY id ASC LIMIT 3"; !slices.Equal(db.queries, []string{want}) {
+		t.Errorf("queries = %q, want %q", db.queries, want)
+	}
+}
+
+func TestCursorSignVerify(t *testing.T) {
+	secret := []byte("secret")
+	when := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+	token, err := encodeCursor(true, "-created_at,id", []any{when, int64(42)}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := decodeCursor(token, secret)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	values, err := c.decodeValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Backward || c.Sort != "-created_at,id" || !reflect.DeepEqual(values, []any{when, int64(42)}) {
+		t.Errorf("decoded cursor = %+v %v", c, values)
+	}
+
+	body, sig, _ := strings.Cut(token, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"-created_at,id","k":"ti","v":["2024-05-06T07:08:09Z","1"]}`))
+	tests := []struct {
+		name   string
+		token  string
+		secret []byte
+	}{
+		{name: "other secret", token: token, secret: []byte("other")},
+		{name: "tampered body", token: tampered + "." + sig, secret: secret},
+		{name: "truncated signature", token: body + "." + sig[:len(sig)-2], secret: secret},
+		{name: "missing signature", token: body, secret: secret},
+		{name: "not base64", token: "!!!.???", secret: secret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.token, tt.secret); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("decodeCursor() error = %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
+
+func TestCursorFloatKeys(t *testing.T) {
+	secret := []byte("secret")
+	key := []any{float32(0.1), 0.1, float32(16777217), 1e-300}
+	token, err := encodeCursor(false, "score,rank", key, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := decodeCursor(token, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := c.decodeValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A REAL key must come back as the same float32, not its float64 widening
+	if !reflect.DeepEqual(values, key) {
+		t.Errorf("decoded keys = %#v, want %#v", values, key)
+	}
+}
+
+func TestCursorSortMismatch(t *testing.T) {
+	db := &fakeDB{}
+	application := &app.App{Config: &config.Config{JwtSecretKey: "jwt"}, Database: db}
+	secret := cursorSecret("", "jwt")
+	token, err := encodeCursor(false, "name,id", []any{"a", int64(1)}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/items?cursor="+token, nil)
+	qb := Select("items", "id", "name").OrderBy("id", "desc")
+	if _, _, err := CursorPaginate(req, application, qb, CursorOptions{}, scanItem); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("CursorPaginate() error = %v, want ErrInvalidQuery", err)
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("queries = %q, want none", db.queries)
+	}
+}
+
+func TestCursorSecret(t *testing.T) {
+	if got := cursorSecret("cursor", "jwt"); string(got) != "cursor" {
+		t.Errorf("cursorSecret() = %q, want CURSOR_SECRET", got)
+	}
+	derived := cursorSecret("", "jwt")
+	if len(derived) == 0 || string(derived) == "jwt" {
+		t.Errorf("cursorSecret() = %q, want a key derived from the JWT key", derived)
+	}
+	if got := cursorSecret("", ""); got != nil {
+		t.Errorf("cursorSecret() = %q, want nil", got)
+	}
+}
@@ -0,0 +1,255 @@
+package utilQuery
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field types used to convert query string values before they are bound
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+)
+
+// queryOps maps the operator names used in filter[field][op] to Builder operators.
+// "null" is special: filter[field][null]=true becomes IS NULL, false IS NOT NULL.
+var queryOps = map[string]string{
+	"eq":    OpEq,
+	"ne":    OpNotEq,
+	"lt":    OpLt,
+	"lte":   OpLte,
+	"gt":    OpGt,
+	"gte":   OpGte,
+	"like":  OpLike,
+	"ilike": OpILike,
+	"in":    OpIn,
+	"nin":   OpNotIn,
+	"null":  OpIsNull,
+}
+
+var filterParam = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// Field is a field clients may filter, sort or select on
+type Field struct {
+	Column string   // SQL column, defaults to the field name
+	Type   string   // one of the Type constants, defaults to TypeString
+	Ops    []string // allowed filter operators (eq, ne, lt, lte, gt, gte, like, ilike, in, nin, null)
+	Sort   bool     // whether the field may appear in sort
+}
+
+// Spec is a module's whitelist for ParseParams
+type Spec struct {
+	Fields      map[string]Field
+	DefaultSort string // used when the request has no sort, e.g. "-created_at"
+}
+
+// Filter is a parsed filter[field][op]=value
+type Filter struct {
+	Column string
+	Op     string // Builder operator
+	Value  any
+}
+
+// Sort is a parsed sort key
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Params is the result of ParseParams
+type Params struct {
+	Filters []Filter
+	Sorts   []Sort
+	Columns []string // from fields=, nil when not given
+}
+
+// ParseParams parses the standard list query grammar against spec:
+//
+//	filter[price][gte]=10   filter[status]=active (eq)   filter[id][in]=1,2,3
+//	sort=-created_at,name   fields=id,name
+//
+// Every problem is reported, joined into one error that IsBadRequest recognises.
+func ParseParams(values url.Values, spec Spec) (*Params, error) {
+	params := &Params{}
+	var errs []error
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		match := filterParam.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		name, op := match[1], match[2]
+		if op == "" {
+			op = "eq"
+		}
+		for _, raw := range values[key] {
+			filter, err := parseFilter(spec, name, op, raw)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			params.Filters = append(params.Filters, filter)
+		}
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = spec.DefaultSort
+	}
+	for _, key := range splitList(sortParam) {
+		name, desc := strings.CutPrefix(key, "-")
+		field, ok := spec.Fields[name]
+		if !ok || !field.Sort {
+			errs = append(errs, fmt.Errorf("%w: cannot sort by %q", ErrUnknownColumn, name))
+			continue
+		}
+		params.Sorts = append(params.Sorts, Sort{Column: field.column(name), Desc: desc})
+	}
+
+	if values.Has("fields") {
+		for _, name := range splitList(values.Get("fields")) {
+			field, ok := spec.Fields[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%w: cannot select %q", ErrUnknownColumn, name))
+				continue
+			}
+			params.Columns = append(params.Columns, field.column(name))
+		}
+		if len(params.Columns) == 0 && len(errs) == 0 {
+			errs = append(errs, fmt.Errorf("%w: fields is empty", ErrInvalidQuery))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return params, nil
+}
+
+func parseFilter(spec Spec, name, op, raw string) (Filter, error) {
+	field, ok := spec.Fields[name]
+	if !ok {
+		return Filter{}, fmt.Errorf("%w: cannot filter by %q", ErrUnknownColumn, name)
+	}
+	builderOp, known := queryOps[op]
+	if !known || !slices.Contains(field.Ops, op) {
+		return Filter{}, fmt.Errorf("%w: operator %q is not allowed on %q", ErrInvalidQuery, op, name)
+	}
+
+	filter := Filter{Column: field.column(name), Op: builderOp}
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%w: filter[%s][null] must be true or false", ErrInvalidQuery, name)
+		}
+		if !isNull {
+			filter.Op = OpIsNotNull
+		}
+	case "in", "nin":
+		list := splitList(raw)
+		values := make([]any, 0, len(list))
+		for _, item := range list {
+			value, err := field.convert(item)
+			if err != nil {
+				return Filter{}, fmt.Errorf("%w: filter[%s][%s]: %v", ErrInvalidQuery, name, op, err)
+			}
+			values = append(values, value)
+		}
+		filter.Value = values
+	default:
+		value, err := field.convert(raw)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%w: filter[%s][%s]: %v", ErrInvalidQuery, name, op, err)
+		}
+		filter.Value = value
+	}
+	return filter, nil
+}
+
+func (f Field) column(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+// convert parses raw according to the field type
+func (f Field) convert(raw string) (any, error) {
+	switch f.Type {
+	case "", TypeString:
+		return raw, nil
+	case TypeInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case TypeFloat:
+		return strconv.ParseFloat(raw, 64)
+	case TypeBool:
+		return strconv.ParseBool(raw)
+	case TypeTime:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+	return nil, fmt.Errorf("unsupported field type %q", f.Type)
+}
+
+// Apply adds the parsed filters, sorts and selected columns to qb
+func (p *Params) Apply(qb *Builder) *Builder {
+	for _, filter := range p.Filters {
+		qb.Where(filter.Column, filter.Op, filter.Value)
+	}
+	for _, s := range p.Sorts {
+		direction := "asc"
+		if s.Desc {
+			direction = "desc"
+		}
+		qb.OrderBy(s.Column, direction)
+	}
+	if len(p.Columns) > 0 {
+		qb.Columns(p.Columns...)
+	}
+	return qb
+}
+
+// IsBadRequest reports whether err was caused by the client's query string
+// (unknown fields, disallowed operators, bad values) and deserves a 400
+func IsBadRequest(err error) bool {
+	return errors.Is(err, ErrUnknownColumn) || errors.Is(err, ErrInvalidQuery)
+}
+
+// ScanDest returns Scan destinations for columns, looked up in targets
+// (column name to pointer). Use it when fields= selects a subset of columns.
+func ScanDest(columns []string, targets map[string]any) []any {
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		dest[i] = targets[column]
+	}
+	return dest
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,164 @@
+package utilQuery
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testSpec = Spec{
+	Fields: map[string]Field{
+		"id":         {Type: TypeInt, Ops: []string{"eq", "in"}, Sort: true},
+		"name":       {Ops: []string{"eq", "ilike"}, Sort: true},
+		"status":     {Type: TypeBool, Ops: []string{"eq"}},
+		"deleted":    {Column: "deleted_at", Ops: []string{"null"}},
+		"created_at": {Type: TypeTime, Ops: []string{"gte"}, Sort: true},
+		"price":      {Type: TypeFloat, Ops: []string{"lt"}},
+	},
+	DefaultSort: "-id",
+}
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *Params
+		wantErr error
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  &Params{Sorts: []Sort{{Column: "id", Desc: true}}},
+		},
+		{
+			name:  "filters, sort and fields",
+			query: "filter[id][in]=1,2&filter[name][ilike]=al&filter[status]=true&filter[price][lt]=9.5&sort=name,-created_at&fields=id,name",
+			want: &Params{
+				Filters: []Filter{
+					{Column: "id", Op: OpIn, Value: []any{int64(1), int64(2)}},
+					{Column: "name", Op: OpILike, Value: "al"},
+					{Column: "price", Op: OpLt, Value: 9.5},
+					{Column: "status", Op: OpEq, Value: true},
+				},
+				Sorts:   []Sort{{Column: "name"}, {Column: "created_at", Desc: true}},
+				Columns: []string{"id", "name"},
+			},
+		},
+		{
+			name:  "null operator and column mapping",
+			query: "filter[deleted][null]=false",
+			want: &Params{
+				Filters: []Filter{{Column: "deleted_at", Op: OpIsNotNull}},
+				Sorts:   []Sort{{Column: "id", Desc: true}},
+			},
+		},
+		{
+			name:  "date only time",
+			query: "filter[created_at][gte]=2024-01-02&sort=id",
+			want: &Params{
+				Filters: []Filter{{Column: "created_at", Op: OpGte, Value: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+				Sorts:   []Sort{{Column: "id"}},
+			},
+		},
+		{
+			name:    "unknown filter column",
+			query:   "filter[password]=x",
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "operator not allowed on field",
+			query:   "filter[status][gt]=1",
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "unknown operator",
+			query:   "filter[id][between]=1",
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "bad value",
+			query:   "filter[id]=abc",
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "bad value in list",
+			query:   "filter[id][in]=1,x",
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "bad null value",
+			query:   "filter[deleted][null]=maybe",
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "field not sortable",
+			query:   "sort=status",
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "unknown sort column",
+			query:   "sort=-password",
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "unknown selected field",
+			query:   "fields=id,password",
+			wantErr: ErrUnknownColumn,
+		},
+		{
+			name:    "empty fields",
+			query:   "fields=,",
+			wantErr: ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseParams(values, testSpec)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseParams() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !IsBadRequest(err) {
+				t.Errorf("IsBadRequest(%v) = false", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseParamsReportsEveryProblem(t *testing.T) {
+	values := url.Values{"filter[password]": {"x"}, "sort": {"status"}}
+	_, err := ParseParams(values, testSpec)
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Fatalf("ParseParams() error = %v", err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("ParseParams() error = %v, want both problems", err)
+	}
+}
+
+func TestParamsApply(t *testing.T) {
+	values, _ := url.ParseQuery("filter[name][ilike]=al&sort=name&fields=id,name")
+	params, err := ParseParams(values, testSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args, err := params.Apply(Select("users", "id", "name", "status")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT id, name FROM users WHERE LOWER(name) LIKE LOWER(?) ORDER BY name ASC"; query != want {
+		t.Errorf("Apply() query = %q, want %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []any{"al"}) {
+		t.Errorf("Apply() args = %v", args)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/JubaerHossain/rootx/pkg/core/app"
 	coreEntity "github.com/JubaerHossain/rootx/pkg/core/entity"
@@ -24,75 +26,50 @@ func Pagination(req *http.Request, app *app.App, table string) (pagination coreE
 
 	// Count total items
 	var totalItems int
-	if err := app.DB.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&totalItems); err != nil {
+	if err := app.Database.QueryRow(ctx, "SELECT COUNT(*) FROM "+table).Scan(&totalItems); err != nil {
 		return coreEntity.Pagination{}, 0, 0, err
 	}
 
-	// Extract limit and offset from query parameters
-	queryValues := req.URL.Query()
-	page, _ := strconv.Atoi(queryValues.Get("page"))
-	if page <= 0 {
-		page = 1
-	}
-	limit, _ := strconv.Atoi(queryValues.Get("limit"))
-	if limit <= 0 {
-		limit = 10
-	}
-	offset := (page - 1) * limit // Default offset (starting from 0 for database query)
-
-	var nextPage, previousPage *int
-	if page > 1 {
-		prevPage := page - 1
-		previousPage = &prevPage
-	}
-	// You may need to adjust the condition based on your total items count
-	if offset+limit < totalItems {
-		nextPageValue := page + 1
-		nextPage = &nextPageValue
-	}
-
-	// Calculate current items on this page
-	currentItems := limit
-	if offset+limit > totalItems {
-		currentItems = totalItems - offset
-	}
+	pagination, limit, offset := pageWindow(req, totalItems)
+	return pagination, limit, offset, nil
+}
 
+// Paginate counts the rows matched by baseQuery+filterQuery and works out the
+// page window. args are bound to the ? placeholders in filterQuery.
+func Paginate(req *http.Request, app *app.App, baseQuery, filterQuery string, args ...any) (coreEntity.Pagination, int, int, error) {
+	ctx := req.Context()
 
-	// Calculate total pages
-	totalPages := totalItems / limit
-	if totalItems%limit != 0 {
-		totalPages++
+	// Count total items with filters applied
+	var totalItems int
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s%s) AS filtered", baseQuery, filterQuery)
+	if err := app.Database.QueryRow(ctx, countQuery, args...).Scan(&totalItems); err != nil {
+		return coreEntity.Pagination{}, 0, 0, err
 	}
 
-	// Prepare pagination struct
-	return coreEntity.Pagination{
-		TotalItems:   totalItems,
-		TotalCurrentItems: currentItems,
-		TotalPages:   totalPages,
-		CurrentPage:  page,
-		NextPage:     nextPage,
-		PreviousPage: previousPage,
-		FirstPage:    1,
-		LastPage:     totalPages,
-	}, limit, offset, nil
+	pagination, limit, offset := pageWindow(req, totalItems)
+	return pagination, limit, offset, nil
 }
 
-func Paginate(req *http.Request, app *app.App, baseQuery, filterQuery string) (coreEntity.Pagination, int, int, error) {
-	ctx := req.Context()
+// PaginateQuery counts the rows matched by qb, then applies the requested
+// page's LIMIT and OFFSET to qb
+func PaginateQuery(req *http.Request, app *app.App, qb *Builder) (coreEntity.Pagination, error) {
+	countQuery, args, err := qb.BuildCount()
+	if err != nil {
+		return coreEntity.Pagination{}, err
+	}
 
-	// Count total items with filters applied
 	var totalItems int
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (%s%s) AS filtered", baseQuery, filterQuery)	
-	if app.Config.DBType == "mysql" {
-		if err := app.MDB.QueryRowContext(ctx, countQuery).Scan(&totalItems); err != nil {
-			return coreEntity.Pagination{}, 0, 0, err
-		}
-	} else {
-		if err := app.DB.QueryRow(ctx, countQuery).Scan(&totalItems); err != nil {
-			return coreEntity.Pagination{}, 0, 0, err
-		}
+	if err := app.Database.QueryRow(req.Context(), countQuery, args...).Scan(&totalItems); err != nil {
+		return coreEntity.Pagination{}, err
 	}
 
+	pagination, limit, offset := pageWindow(req, totalItems)
+	qb.Limit(limit).Offset(offset)
+	return pagination, nil
+}
+
+// pageWindow reads page and limit from the query string
+func pageWindow(req *http.Request, totalItems int) (coreEntity.Pagination, int, int) {
 	// Extract limit and offset from query parameters
 	queryValues := req.URL.Query()
 	page, _ := strconv.Atoi(queryValues.Get("page"))
@@ -118,7 +95,7 @@ func Paginate(req *http.Request, app *app.App, baseQuery, filterQuery string) (c
 	// Calculate current items on this page
 	currentItems := limit
 	if offset+limit > totalItems {
-		currentItems = totalItems - offset
+		currentItems = max(totalItems-offset, 0)
 	}
 
 	// Calculate total pages
@@ -129,15 +106,15 @@ func Paginate(req *http.Request, app *app.App, baseQuery, filterQuery string) (c
 
 	// Prepare pagination struct
 	return coreEntity.Pagination{
-		TotalItems:   totalItems,
+		TotalItems:        totalItems,
 		TotalCurrentItems: currentItems,
-		TotalPages:   totalPages,
-		CurrentPage:  page,
-		NextPage:     nextPage,
-		PreviousPage: previousPage,
-		FirstPage:    1,
-		LastPage:     totalPages,
-	}, limit, offset, nil
+		TotalPages:        totalPages,
+		CurrentPage:       page,
+		NextPage:          nextPage,
+		PreviousPage:      previousPage,
+		FirstPage:         1,
+		LastPage:          totalPages,
+	}, limit, offset
 }
 
 func RawPagination(sqlQuery string, queryValues map[string][]string) string {
@@ -216,21 +193,24 @@ func Round(num float64, places int) float64 {
 	return rounded
 }
 
-func OrderBy(queryValues map[string][]string) string {
+// OrderBy returns an "column direction" clause from the orderBy and sortBy
+// query parameters. The column must be one of allowed (or, when none are
+// given, a plain identifier) and the direction asc or desc; anything else
+// falls back to "created_at asc".
+func OrderBy(queryValues map[string][]string, allowed ...string) string {
 	q := url.Values(queryValues)
 	orderBy := "created_at"
-	if conditions, ok := q["orderBy"]; ok && len(conditions) > 0 {
-		orderBy = conditions[0]
+	if column := q.Get("orderBy"); column != "" && identifier.MatchString(column) &&
+		(len(allowed) == 0 || slices.Contains(allowed, column)) {
+		orderBy = column
 	}
 
 	sortOrder := "asc"
-	if conditions, ok := q["sortBy"]; ok && len(conditions) > 0 {
-		sortOrder = conditions[0]
+	if strings.EqualFold(q.Get("sortBy"), "desc") {
+		sortOrder = "desc"
 	}
 
-	orderBy = orderBy + " " + sortOrder
-
-	return orderBy
+	return orderBy + " " + sortOrder
 }
 
 func GenerateUniqueNumber(length int) (string, error) {
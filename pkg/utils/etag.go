@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPreconditionFailed is returned when a request's If-Match header does not
+// match the current ETag of the resource
+var ErrPreconditionFailed = errors.New("resource has been modified, reload and try again")
+
+// ETag returns the entity tag for a resource at version
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag sets the ETag response header
+func SetETag(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+}
+
+// IfMatch reports whether the request may modify a resource whose current tag
+// is etag: true when If-Match is absent, "*", or lists etag. The comparison is
+// strong (RFC 7232 section 3.1), so a weak tag (W/"1") never matches.
+func IfMatch(r *http.Request, etag string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return true
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIfMatch returns ErrPreconditionFailed when IfMatch fails
+func CheckIfMatch(r *http.Request, etag string) error {
+	if !IfMatch(r, etag) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
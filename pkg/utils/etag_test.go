@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		want   bool
+	}{
+		{name: "no header", header: "", etag: `"3"`, want: true},
+		{name: "any", header: "*", etag: `"3"`, want: true},
+		{name: "same tag", header: `"3"`, etag: `"3"`, want: true},
+		{name: "one of a list", header: `"1", "3"`, etag: `"3"`, want: true},
+		{name: "other tag", header: `"2"`, etag: `"3"`, want: false},
+		{name: "weak request tag", header: `W/"3"`, etag: `"3"`, want: false},
+		{name: "weak current tag", header: `W/"3"`, etag: `W/"3"`, want: false},
+		{name: "unquoted tag", header: `3`, etag: `"3"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/posts/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			if got := IfMatch(r, tt.etag); got != tt.want {
+				t.Errorf("IfMatch(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+			}
+			err := CheckIfMatch(r, tt.etag)
+			if tt.want != (err == nil) || (err != nil && !errors.Is(err, ErrPreconditionFailed)) {
+				t.Errorf("CheckIfMatch() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestETag(t *testing.T) {
+	if got := ETag(42); got != `"42"` {
+		t.Errorf("ETag(42) = %s", got)
+	}
+}